@@ -0,0 +1,189 @@
+package errm
+
+import (
+	"encoding/json"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Catalog maps error codes and sentinel errors to localized message templates that are safe to show to users.
+// Templates have the same placeholders as [T] and they are filled with fields of the error:
+//
+//	catalog := errm.NewCatalog("en")
+//	catalog.Add(errm.NotFound, "en", "Order {order_id} not found")
+//	catalog.Add(errm.NotFound, "ru", "Заказ {order_id} не найден")
+//
+//	err := errm.Wrap(errm.New("no rows", errm.NotFound), "cannot load", "order_id", 42)
+//	catalog.Localize(err, "ru") // Заказ 42 не найден
+//
+// It is safe for concurrent/parallel usage.
+type Catalog struct {
+	mu        sync.RWMutex
+	fallback  string
+	messages  map[string]map[string]string // key -> language -> template
+	sentinels []catalogSentinel
+}
+
+type catalogSentinel struct {
+	err error
+	key string
+}
+
+// DefaultCatalog is the catalog used by [Localize], its fallback language is English.
+var DefaultCatalog = NewCatalog("en")
+
+// NewCatalog returns an empty [Catalog] with the language that is used if there is no template for a requested language.
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback: normalizeLang(fallback),
+		messages: make(map[string]map[string]string),
+	}
+}
+
+// Add adds a template of a message for errors with the code in the language, e.g. "en" or "pt-BR".
+func (c *Catalog) Add(code Code, lang, template string) {
+	c.add(string(code), lang, template)
+}
+
+// AddSentinel adds a template of a message for errors that match the sentinel error using [Is].
+// Sentinels are checked before codes in the order they were added.
+func (c *Catalog) AddSentinel(sentinel error, lang, template string) {
+	key := "sentinel:" + sentinel.Error()
+	c.mu.Lock()
+	if _, ok := c.messages[key]; !ok {
+		c.sentinels = append(c.sentinels, catalogSentinel{err: sentinel, key: key})
+	}
+	c.mu.Unlock()
+	c.add(key, lang, template)
+}
+
+// Sentinel makes errors that match the sentinel error using [Is] use templates with the key instead of templates
+// of their codes. It is useful with templates loaded using [Catalog.Load]:
+//
+//	catalog.Sentinel(ErrOrderNotFound, "order_not_found")
+func (c *Catalog) Sentinel(sentinel error, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sentinels = append(c.sentinels, catalogSentinel{err: sentinel, key: key})
+}
+
+func (c *Catalog) add(key, lang, template string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	byLang, ok := c.messages[key]
+	if !ok {
+		byLang = make(map[string]string)
+		c.messages[key] = byLang
+	}
+	byLang[normalizeLang(lang)] = template
+}
+
+// Load adds templates from JSON files in the file system that match the pattern, e.g. files embedded using embed.FS.
+// The name of a file without the extension is the language, e.g. "locales/ru.json", and the file is an object
+// with codes or keys of sentinels and templates:
+//
+//	{"not_found": "Заказ {order_id} не найден", "order_canceled": "Заказ {order_id} отменен"}
+func (c *Catalog) Load(fsys fs.FS, pattern string) error {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return Wrap(err, "cannot match files", "pattern", pattern)
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return Wrap(err, "cannot read file", "file", file)
+		}
+		var templates map[string]string
+		if err := json.Unmarshal(data, &templates); err != nil {
+			return Wrap(err, "cannot decode file", "file", file)
+		}
+		lang := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		for key, template := range templates {
+			c.add(key, lang, template)
+		}
+	}
+	return nil
+}
+
+// Localize returns the message of the error in the language from the template of the first matching sentinel
+// or the template of the code of the error. The language falls back from a regional variant to the base language,
+// e.g. from "pt-BR" to "pt", and then to the fallback language of the catalog.
+// It returns [PublicMessage] if there is no template or the error has no field for a placeholder of the template,
+// so raw placeholders are never shown to users. It returns an empty string for a nil error.
+func (c *Catalog) Localize(err error, lang string) string {
+	if err == nil {
+		return ""
+	}
+	if template, ok := c.template(err, lang); ok {
+		return localizeTemplate(template, err)
+	}
+	return PublicMessage(err)
+}
+
+// Localize returns the message of the error in the language using [DefaultCatalog], see [Catalog.Localize].
+func Localize(err error, lang string) string {
+	return DefaultCatalog.Localize(err, lang)
+}
+
+func (c *Catalog) template(err error, lang string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.sentinels {
+		if Is(err, s.err) {
+			if template, ok := c.lookup(s.key, lang); ok {
+				return template, true
+			}
+		}
+	}
+	if code := CodeOf(err); code != "" {
+		return c.lookup(string(code), lang)
+	}
+	return "", false
+}
+
+func (c *Catalog) lookup(key, lang string) (string, bool) {
+	byLang, ok := c.messages[key]
+	if !ok {
+		return "", false
+	}
+	lang = normalizeLang(lang)
+	for {
+		if template, ok := byLang[lang]; ok {
+			return template, true
+		}
+		i := strings.LastIndexByte(lang, '-')
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	template, ok := byLang[c.fallback]
+	return template, ok
+}
+
+// localizeTemplate fills placeholders of the template with fields of the error, the outermost field with a key is used.
+// It returns [PublicMessage] if the error has no field for a placeholder.
+func localizeTemplate(template string, err error) string {
+	fields := Fields(err)
+	seen := make(map[any]bool, len(fields)/2)
+	unique := make([]any, 0, len(fields))
+	for i := 0; i+1 < len(fields); i += 2 {
+		if !seen[fields[i]] {
+			seen[fields[i]] = true
+			unique = append(unique, fields[i], fields[i+1])
+		}
+	}
+	msg, _, _, missing := renderTemplate(template, unique)
+	if missing {
+		return PublicMessage(err)
+	}
+	return msg
+}
+
+// normalizeLang returns a language tag in lower case with "-" as a separator, e.g. "pt-br" for "pt_BR".
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+}
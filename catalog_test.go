@@ -0,0 +1,61 @@
+package errm_test
+
+import (
+	"embed"
+	"io"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+//go:embed testdata/locales/*.json
+var locales embed.FS
+
+func TestCatalog(t *testing.T) {
+	errCanceled := errm.New("order is canceled", errm.FailedPrecondition)
+
+	c := errm.NewCatalog("en")
+	if err := c.Load(locales, "testdata/locales/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	c.Sentinel(errCanceled, "order_canceled")
+	c.AddSentinel(io.ErrUnexpectedEOF, "en", "Upload of {file} was interrupted")
+	c.Add(errm.Unavailable, "en", "Service is unavailable")
+
+	notFound := errm.Wrap(errm.New("no rows", errm.NotFound, "order_id", 1), "cannot load", "order_id", 42, "host", "db")
+
+	testCases := []struct {
+		id   string
+		err  error
+		lang string
+		exp  string
+	}{
+		{id: "nil", err: nil, lang: "en", exp: ""},
+		{id: "en", err: notFound, lang: "en", exp: "Order 42 not found"},
+		{id: "ru", err: notFound, lang: "ru", exp: "Заказ 42 не найден"},
+		{id: "region", err: notFound, lang: "ru_RU", exp: "Заказ 42 не найден"},
+		{id: "exact_region", err: notFound, lang: "pt-br", exp: "Pedido 42 não encontrado"},
+		{id: "fallback", err: notFound, lang: "de", exp: "Order 42 not found"},
+		{id: "fallback_lang", err: errm.New("down", errm.Unavailable), lang: "ru", exp: "Service is unavailable"},
+		{id: "sentinel", err: errm.Wrap(errCanceled, "cannot pay", "order_id", 7), lang: "ru", exp: "Заказ 7 отменен"},
+		{id: "external_sentinel", err: errm.Wrap(io.ErrUnexpectedEOF, "upload", "file", "a.txt"), lang: "en", exp: "Upload of a.txt was interrupted"},
+		{id: "missing_field", err: errm.New("no rows", errm.NotFound), lang: "en", exp: "Not found"},
+		{id: "no_template", err: errm.New("denied", errm.PermissionDenied), lang: "en", exp: "Permission denied"},
+		{id: "public", err: errm.WithPublic(errm.New("x"), "Try again"), lang: "ru", exp: "Try again"},
+		{id: "no_code", err: errm.New("connection refused host=db"), lang: "en", exp: "Internal error"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if got := c.Localize(tc.err, tc.lang); got != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+
+	if err := c.Load(locales, "testdata/locales/["); err == nil {
+		t.Errorf("expected an error for a bad pattern")
+	}
+	if got := errm.Localize(notFound, "en"); got != "Not found" {
+		t.Errorf("expected %q, got %q", "Not found", got)
+	}
+}
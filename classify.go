@@ -0,0 +1,133 @@
+package errm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+)
+
+// Category is a kind of an error of the standard library or the operating system, see [Classify].
+type Category string
+
+// Categories of errors returned by [Classify].
+const (
+	CategoryUnknown     Category = ""
+	CategoryNotFound    Category = "not_found"
+	CategoryExists      Category = "exists"
+	CategoryPermission  Category = "permission"
+	CategoryInvalid     Category = "invalid"
+	CategoryTimeout     Category = "timeout"
+	CategoryCanceled    Category = "canceled"
+	CategoryUnavailable Category = "unavailable"
+	CategoryExhausted   Category = "exhausted"
+	CategoryCorrupt     Category = "corrupt"
+)
+
+// Classify returns the category of the first error of the standard library or the operating system in err's chain:
+//
+//   - [CategoryNotFound]: [fs.ErrNotExist], sql.ErrNoRows, ENOENT;
+//   - [CategoryExists]: [fs.ErrExist], EEXIST;
+//   - [CategoryPermission]: [fs.ErrPermission], EACCES, EPERM;
+//   - [CategoryInvalid]: [fs.ErrInvalid], EINVAL;
+//   - [CategoryTimeout]: [context.DeadlineExceeded], [os.ErrDeadlineExceeded], [net.Error] with Timeout(), ETIMEDOUT;
+//   - [CategoryCanceled]: [context.Canceled];
+//   - [CategoryUnavailable]: connection refused, reset and aborted, unreachable hosts and networks, broken pipes,
+//     [net.DNSError] and [net.ErrClosed];
+//   - [CategoryExhausted]: no space left on a device, exceeded quotas and too many open files;
+//   - [CategoryCorrupt]: [io.ErrUnexpectedEOF].
+//
+// Use [Category.Code] to add a code to an external error when you wrap it:
+//
+//	errm.Wrap(err, "cannot open config", errm.Classify(err).Code(), "path", path)
+//
+// It returns [CategoryUnknown] for nil and other errors. System error numbers are not classified on Plan 9.
+func Classify(err error) Category {
+	if err == nil {
+		return CategoryUnknown
+	}
+	errno := errnoCategory(err)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded), isTimeout(err):
+		return CategoryTimeout
+	case errors.Is(err, fs.ErrNotExist), hasLeafMessage(err, errNoRowsMessage):
+		return CategoryNotFound
+	case errors.Is(err, fs.ErrExist):
+		return CategoryExists
+	case errors.Is(err, fs.ErrPermission):
+		return CategoryPermission
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return CategoryCorrupt
+	case errno == CategoryExhausted:
+		return CategoryExhausted
+	case errno == CategoryUnavailable, errors.Is(err, net.ErrClosed):
+		return CategoryUnavailable
+	case errors.Is(err, fs.ErrInvalid), errno == CategoryInvalid:
+		return CategoryInvalid
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return CategoryUnavailable
+	}
+	return CategoryUnknown
+}
+
+// Code returns the code that corresponds to the category, e.g. [NotFound] for [CategoryNotFound].
+// It returns an empty code for [CategoryUnknown].
+func (c Category) Code() Code {
+	switch c {
+	case CategoryNotFound:
+		return NotFound
+	case CategoryExists:
+		return AlreadyExists
+	case CategoryPermission:
+		return PermissionDenied
+	case CategoryInvalid:
+		return InvalidArgument
+	case CategoryTimeout:
+		return DeadlineExceeded
+	case CategoryCanceled:
+		return Canceled
+	case CategoryUnavailable:
+		return Unavailable
+	case CategoryExhausted:
+		return ResourceExhausted
+	case CategoryCorrupt:
+		return Internal
+	}
+	return ""
+}
+
+// isTimeout reports whether any error in err's chain is a timeout like [net.Error] with Timeout().
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
+
+// errNoRowsMessage is the message of sql.ErrNoRows, it is matched by the message,
+// so the package doesn't depend on database/sql.
+const errNoRowsMessage = "sql: no rows in result set"
+
+// hasLeafMessage reports whether any error in err's chain that doesn't wrap other errors has the message.
+func hasLeafMessage(err error, msg string) bool {
+	for err != nil {
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				if hasLeafMessage(inner, msg) {
+					return true
+				}
+			}
+			return false
+		default:
+			return err.Error() == msg
+		}
+	}
+	return false
+}
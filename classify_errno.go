@@ -0,0 +1,32 @@
+//go:build !plan9
+
+package errm
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errnoCategory returns the category of a system error number in err's chain.
+func errnoCategory(err error) Category {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return CategoryUnknown
+	}
+	switch errno {
+	case syscall.ENOSPC, syscall.EDQUOT, syscall.EMFILE, syscall.ENFILE:
+		return CategoryExhausted
+	case syscall.ECONNREFUSED, syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EHOSTUNREACH,
+		syscall.ENETUNREACH, syscall.ENETDOWN, syscall.EPIPE:
+		return CategoryUnavailable
+	case syscall.EINVAL:
+		return CategoryInvalid
+	}
+	return CategoryUnknown
+}
+
+// isConnReset reports whether err is a connection reset by the peer.
+func isConnReset(err error) bool {
+	errno, ok := err.(syscall.Errno)
+	return ok && errno == syscall.ECONNRESET
+}
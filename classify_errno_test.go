@@ -0,0 +1,52 @@
+//go:build !plan9
+
+package errm_test
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestClassifyErrno(t *testing.T) {
+	testCases := []struct {
+		id   string
+		err  error
+		exp  errm.Category
+		code errm.Code
+	}{
+		{id: "enoent", err: syscall.ENOENT, exp: errm.CategoryNotFound, code: errm.NotFound},
+		{id: "exists", err: &fs.PathError{Op: "mkdir", Path: "a", Err: syscall.EEXIST}, exp: errm.CategoryExists, code: errm.AlreadyExists},
+		{id: "eacces", err: &fs.PathError{Op: "open", Path: "a", Err: syscall.EACCES}, exp: errm.CategoryPermission, code: errm.PermissionDenied},
+		{id: "etimedout", err: syscall.ETIMEDOUT, exp: errm.CategoryTimeout, code: errm.DeadlineExceeded},
+		{id: "refused", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, exp: errm.CategoryUnavailable, code: errm.Unavailable},
+		{id: "reset", err: errm.Wrap(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, "x"), exp: errm.CategoryUnavailable, code: errm.Unavailable},
+		{id: "no_space", err: &fs.PathError{Op: "write", Path: "a", Err: syscall.ENOSPC}, exp: errm.CategoryExhausted, code: errm.ResourceExhausted},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			got := errm.Classify(tc.err)
+			if got != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+			if code := got.Code(); code != tc.code {
+				t.Errorf("expected %q, got %q", tc.code, code)
+			}
+		})
+	}
+}
+
+func TestIsRetryableErrno(t *testing.T) {
+	err := errm.Wrap(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, "query")
+	if !errm.IsRetryable(err) {
+		t.Errorf("expected true, got %t", errm.IsRetryable(err))
+	}
+	err = errm.Wrap(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, "query")
+	if errm.IsRetryable(err) {
+		t.Errorf("expected false, got %t", errm.IsRetryable(err))
+	}
+}
@@ -0,0 +1,12 @@
+package errm
+
+// errnoCategory returns the category of a system error number in err's chain.
+// Plan 9 reports system errors as strings, so there are no numbers to classify.
+func errnoCategory(error) Category {
+	return CategoryUnknown
+}
+
+// isConnReset reports whether err is a connection reset by the peer.
+func isConnReset(error) bool {
+	return false
+}
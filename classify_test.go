@@ -0,0 +1,59 @@
+package errm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestClassify(t *testing.T) {
+	_, openErr := os.Open("testdata/not-exists")
+
+	testCases := []struct {
+		id   string
+		err  error
+		exp  errm.Category
+		code errm.Code
+	}{
+		{id: "nil", err: nil, exp: errm.CategoryUnknown},
+		{id: "unknown", err: io.EOF, exp: errm.CategoryUnknown},
+		{id: "errm", err: errm.New("x"), exp: errm.CategoryUnknown},
+		{id: "not_exist", err: openErr, exp: errm.CategoryNotFound, code: errm.NotFound},
+		{id: "no_rows", err: errm.Wrap(sql.ErrNoRows, "cannot load"), exp: errm.CategoryNotFound, code: errm.NotFound},
+		{id: "no_rows_joined", err: fmt.Errorf("query: %w", errors.Join(io.EOF, sql.ErrNoRows)), exp: errm.CategoryNotFound, code: errm.NotFound},
+		{id: "no_rows_wrapper", err: fmt.Errorf("%w: sql: no rows in result set", io.EOF), exp: errm.CategoryUnknown},
+		{id: "permission", err: fs.ErrPermission, exp: errm.CategoryPermission, code: errm.PermissionDenied},
+		{id: "invalid", err: fs.ErrInvalid, exp: errm.CategoryInvalid, code: errm.InvalidArgument},
+		{id: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), exp: errm.CategoryTimeout, code: errm.DeadlineExceeded},
+		{id: "os_deadline", err: &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}, exp: errm.CategoryTimeout, code: errm.DeadlineExceeded},
+		{id: "dns_timeout", err: &net.DNSError{Err: "timeout", IsTimeout: true}, exp: errm.CategoryTimeout, code: errm.DeadlineExceeded},
+		{id: "canceled", err: errm.Wrap(context.Canceled, "query"), exp: errm.CategoryCanceled, code: errm.Canceled},
+		{id: "dns", err: &net.DNSError{Err: "no such host", Name: "db", IsNotFound: true}, exp: errm.CategoryUnavailable, code: errm.Unavailable},
+		{id: "closed", err: net.ErrClosed, exp: errm.CategoryUnavailable, code: errm.Unavailable},
+		{id: "unexpected_eof", err: errm.Wrap(io.ErrUnexpectedEOF, "decode"), exp: errm.CategoryCorrupt, code: errm.Internal},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			got := errm.Classify(tc.err)
+			if got != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+			if code := got.Code(); code != tc.code {
+				t.Errorf("expected %q, got %q", tc.code, code)
+			}
+		})
+	}
+
+	err := errm.Wrap(openErr, "cannot open config", errm.Classify(openErr).Code())
+	if !errm.IsCode(err, errm.NotFound) {
+		t.Errorf("expected not found, got %q", errm.CodeOf(err))
+	}
+}
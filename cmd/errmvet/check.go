@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/maxbolgarin/errm/internal/fmtscan"
+)
+
+const errmPath = "github.com/maxbolgarin/errm"
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+// checker finds problems in calls of the errm package in type checked files.
+type checker struct {
+	fset  *token.FileSet
+	pkg   *types.Package
+	info  *types.Info
+	diags []diagnostic
+}
+
+func (c *checker) report(pos token.Pos, format string, args ...any) {
+	c.diags = append(c.diags, diagnostic{pos: c.fset.Position(pos), msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) checkFile(f *ast.File) {
+	var stack []ast.Node
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+		if call, ok := n.(*ast.CallExpr); ok {
+			c.checkCall(call, stack)
+		}
+		return true
+	})
+}
+
+// checkCall checks calls of functions and methods of the errm package that take a message and fields:
+// New, Errorf, Wrap, Wrapf, WrapMany and T and methods of collectors and factories with the same names,
+// and fields of With and BaseFields.
+func (c *checker) checkCall(call *ast.CallExpr, stack []ast.Node) {
+	fn := c.errmFunc(call)
+	if fn == nil {
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+	params := sig.Params()
+	if fn.Name() == "With" || fn.Name() == "BaseFields" {
+		// Fields follow the error in errm.With, other functions take only fields.
+		if first := params.Len() - 1; !call.Ellipsis.IsValid() && len(call.Args) >= first {
+			c.checkFields(funcName(fn), call.Args[first:])
+		}
+		return
+	}
+	msgIndex := params.Len() - 2
+	if !sig.Variadic() || msgIndex < 0 || !isString(params.At(msgIndex).Type()) || len(call.Args) <= msgIndex {
+		return
+	}
+	name := funcName(fn)
+
+	if msgIndex > 0 && isError(params.At(0).Type()) && fn.Name() != "WrapMany" {
+		c.checkNilWrap(name, call.Args[0], stack)
+	}
+	if call.Ellipsis.IsValid() {
+		return
+	}
+
+	args := call.Args[msgIndex+1:]
+	tv := c.info.Types[call.Args[msgIndex]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		if !strings.HasSuffix(name, "f") {
+			c.checkFields(name, args)
+		}
+		return
+	}
+	msg := constant.StringVal(tv.Value)
+	verbs, argsNum := fmtscan.Scan(msg)
+
+	if !strings.HasSuffix(name, "f") {
+		for _, v := range verbs {
+			if v.Verb == 'w' {
+				c.report(call.Args[msgIndex].Pos(), "%s message has %%w verb, use a function with formatting to wrap errors", name)
+				break
+			}
+		}
+		c.checkFields(name, args)
+		return
+	}
+
+	for _, v := range verbs {
+		switch {
+		case v.Arg < 0:
+			c.report(call.Args[msgIndex].Pos(), "%s format has %%%c verb with a bad argument index", name, v.Verb)
+		case v.Verb == 'w' && v.Arg < len(args):
+			if t := c.info.TypeOf(args[v.Arg]); t != nil && !isError(t) {
+				c.report(args[v.Arg].Pos(), "%s format %%w has operand %s of type %s that is not an error", name, types.ExprString(args[v.Arg]), c.typeString(t))
+			}
+		}
+	}
+	if argsNum > len(args) {
+		c.report(call.Lparen, "%s format %s needs %d args, but call has %d", name, strconv.Quote(msg), argsNum, len(args))
+		return
+	}
+	c.checkFields(name, args[argsNum:])
+}
+
+// checkFields reports fields that are rendered with !BADKEY and !MISSING markers, non-constant and duplicate keys.
+func (c *checker) checkFields(name string, fields []ast.Expr) {
+	seen := make(map[string]bool)
+	addKey := func(e ast.Expr, key string) {
+		if seen[key] {
+			c.report(e.Pos(), "%s has duplicate key %q", name, key)
+		}
+		seen[key] = true
+	}
+
+	for i := 0; i < len(fields); i++ {
+		e := fields[i]
+		t := c.info.TypeOf(e)
+		if t == nil {
+			continue
+		}
+		if isErrmType(t, "Field") {
+			if key, ok := c.fieldKey(e); ok {
+				addKey(e, key)
+			}
+			continue
+		}
+		if isErrmType(t, "Code") {
+			continue
+		}
+		if !isString(t) {
+			c.report(e.Pos(), "%s key %s of type %s is not a string", name, types.ExprString(e), c.typeString(t))
+			continue
+		}
+		if i+1 >= len(fields) {
+			c.report(e.Pos(), "%s key %s has no value", name, types.ExprString(e))
+			return
+		}
+		if tv := c.info.Types[e]; tv.Value == nil {
+			c.report(e.Pos(), "%s key %s is not a constant", name, types.ExprString(e))
+		} else {
+			addKey(e, constant.StringVal(tv.Value))
+		}
+		i++
+	}
+}
+
+// checkNilWrap reports wraps of nil errors, they create a new error instead of returning nil.
+// The error is nil if it is the nil literal or if it is checked to be nil by an enclosing if statement.
+func (c *checker) checkNilWrap(name string, arg ast.Expr, stack []ast.Node) {
+	if tv := c.info.Types[arg]; tv.IsNil() {
+		c.report(arg.Pos(), "%s of nil creates a new error", name)
+		return
+	}
+	id, ok := ast.Unparen(arg).(*ast.Ident)
+	if !ok || c.info.Uses[id] == nil {
+		return
+	}
+	obj := c.info.Uses[id]
+
+	for i := len(stack) - 2; i > 0; i-- {
+		ifStmt, ok := stack[i-1].(*ast.IfStmt)
+		if !ok {
+			continue
+		}
+		op, ok := c.nilCheck(ifStmt.Cond, obj)
+		if !ok {
+			continue
+		}
+		if (op == token.EQL && stack[i] == ifStmt.Body) || (op == token.NEQ && stack[i] == ifStmt.Else) {
+			if !c.assignedBefore(stack[i], obj, arg.Pos()) {
+				c.report(arg.Pos(), "%s of %s that is nil creates a new error", name, id.Name)
+			}
+			return
+		}
+	}
+}
+
+// nilCheck returns the operator of a condition like "err == nil" or "err != nil" for the given object.
+func (c *checker) nilCheck(cond ast.Expr, obj types.Object) (token.Token, bool) {
+	b, ok := ast.Unparen(cond).(*ast.BinaryExpr)
+	if !ok || (b.Op != token.EQL && b.Op != token.NEQ) {
+		return 0, false
+	}
+	x, y := b.X, b.Y
+	if c.info.Types[x].IsNil() {
+		x, y = y, x
+	}
+	id, ok := ast.Unparen(x).(*ast.Ident)
+	if !ok || c.info.Uses[id] != obj || !c.info.Types[y].IsNil() {
+		return 0, false
+	}
+	return b.Op, true
+}
+
+// assignedBefore reports whether the object is assigned or its address is taken in the node before pos.
+func (c *checker) assignedBefore(node ast.Node, obj types.Object, pos token.Pos) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found || n == nil || n.Pos() >= pos {
+			return false
+		}
+		var targets []ast.Expr
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			targets = n.Lhs
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				targets = []ast.Expr{n.X}
+			}
+		}
+		for _, e := range targets {
+			if id, ok := ast.Unparen(e).(*ast.Ident); ok && c.info.Uses[id] == obj {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// errmFunc returns the called function or method if it is declared in the errm package and takes fields.
+func (c *checker) errmFunc(call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, ok := c.info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != errmPath {
+		return nil
+	}
+	switch fn.Name() {
+	case "New", "Errorf", "Wrap", "Wrapf", "WrapMany", "T", "With", "BaseFields":
+		return fn
+	}
+	return nil
+}
+
+// fieldKey returns the key of a field created by a constructor like errm.String("key", value) with a constant key.
+func (c *checker) fieldKey(e ast.Expr) (string, bool) {
+	call, ok := ast.Unparen(e).(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return "", false
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	if fn, ok := c.info.Uses[sel.Sel].(*types.Func); !ok || fn.Pkg() == nil || fn.Pkg().Path() != errmPath {
+		return "", false
+	}
+	tv := c.info.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+func (c *checker) typeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(c.pkg))
+}
+
+// funcName returns a name like errm.Wrap or errm.List.Wrap.
+func funcName(fn *types.Func) string {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return "errm." + fn.Name()
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return "errm." + named.Obj().Name() + "." + fn.Name()
+	}
+	return "errm." + fn.Name()
+}
+
+// isString returns true for string and untyped string types, values of named string types are not string keys.
+func isString(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && (b.Kind() == types.String || b.Kind() == types.UntypedString)
+}
+
+func isError(t types.Type) bool {
+	return types.Implements(t, errorType)
+}
+
+func isErrmType(t types.Type, name string) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == name && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == errmPath
+}
@@ -0,0 +1,194 @@
+// Errmvet reports suspicious calls of the errm package like go vet does it for fmt:
+// malformed field lists, duplicate keys, formats that need more arguments than provided,
+// misused %w verbs and wraps of errors that are known to be nil.
+//
+//	go run github.com/maxbolgarin/errm/cmd/errmvet ./...
+//
+// It uses only the standard library and the go command to load packages, test files are checked too.
+// It exits with a non-zero status if it finds any problem.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: errmvet [packages]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	diags, err := run(patterns)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "errmvet:", err)
+		os.Exit(1)
+	}
+	for _, d := range diags {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if len(diags) > 0 {
+		os.Exit(1)
+	}
+}
+
+// diagnostic is a problem found at a position.
+type diagnostic struct {
+	pos token.Position
+	msg string
+}
+
+func (d diagnostic) String() string {
+	return d.pos.String() + ": " + d.msg
+}
+
+// listedPackage is a package reported by go list.
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	Export     string
+	DepOnly    bool
+	ImportMap  map[string]string
+	Error      *struct{ Err string }
+}
+
+// run loads packages matching the patterns with their test files and returns sorted diagnostics.
+func run(patterns []string) ([]diagnostic, error) {
+	pkgs, err := listPackages(patterns)
+	if err != nil {
+		return nil, err
+	}
+	exports := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		exports[p.ImportPath] = p.Export
+	}
+
+	var (
+		diags []diagnostic
+		errs  []error
+		fset  = token.NewFileSet()
+	)
+	for _, p := range pkgs {
+		// Generated test mains are listed as "path.test".
+		if p.DepOnly || strings.HasSuffix(p.ImportPath, ".test") {
+			continue
+		}
+		if p.Error != nil {
+			errs = append(errs, errors.New(p.Error.Err))
+			continue
+		}
+		found, err := checkPackage(fset, p, exports)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.ImportPath, err))
+			continue
+		}
+		diags = append(diags, found...)
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		for i, d := range diags {
+			if rel, err := filepath.Rel(wd, d.pos.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+				diags[i].pos.Filename = rel
+			}
+		}
+	}
+	slices.SortFunc(diags, func(a, b diagnostic) int {
+		if c := strings.Compare(a.pos.Filename, b.pos.Filename); c != 0 {
+			return c
+		}
+		if a.pos.Offset != b.pos.Offset {
+			return a.pos.Offset - b.pos.Offset
+		}
+		return strings.Compare(a.msg, b.msg)
+	})
+	// Files of a package are checked twice: with and without its test files.
+	diags = slices.Compact(diags)
+
+	return diags, errors.Join(errs...)
+}
+
+// listPackages returns packages matching the patterns, their test variants and dependencies with export data.
+func listPackages(patterns []string) ([]listedPackage, error) {
+	args := append([]string{"list", "-e", "-test", "-deps", "-export",
+		"-json=ImportPath,Dir,GoFiles,Export,DepOnly,ImportMap,Error"}, patterns...)
+	cmd := exec.Command("go", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, stderr.String())
+	}
+
+	var pkgs []listedPackage
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var p listedPackage
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decode go list output: %w", err)
+		}
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// checkPackage parses and type checks files of the package using export data of its dependencies.
+func checkPackage(fset *token.FileSet, p listedPackage, exports map[string]string) ([]diagnostic, error) {
+	files := make([]*ast.File, 0, len(p.GoFiles))
+	for _, name := range p.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(p.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	lookup := func(path string) (io.ReadCloser, error) {
+		if mapped, ok := p.ImportMap[path]; ok {
+			path = mapped
+		}
+		export, ok := exports[path]
+		if !ok || export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "gc", lookup)}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	// Test variants are listed as "path [path.test]".
+	path, _, _ := strings.Cut(p.ImportPath, " ")
+	pkg, err := conf.Check(path, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{fset: fset, pkg: pkg, info: info}
+	for _, f := range files {
+		c.checkFile(f)
+	}
+	return c.diags, nil
+}
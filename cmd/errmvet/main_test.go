@@ -0,0 +1,63 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var wantRe = regexp.MustCompile("`([^`]*)`")
+
+func TestRun(t *testing.T) {
+	diags, err := run([]string{"./testdata/calls"})
+	if err != nil {
+		t.Fatalf("expected nil, got %s", err)
+	}
+
+	// Expectations are written as // want `regexp` comments on lines with problems.
+	want := make(map[int][]*regexp.Regexp)
+	name := filepath.Join("testdata", "calls", "calls.go")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("expected nil, got %s", err)
+	}
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			text, ok := strings.CutPrefix(c.Text, "// want ")
+			if !ok {
+				continue
+			}
+			line := fset.Position(c.Pos()).Line
+			for _, m := range wantRe.FindAllStringSubmatch(text, -1) {
+				want[line] = append(want[line], regexp.MustCompile(m[1]))
+			}
+		}
+	}
+
+	for _, d := range diags {
+		if d.pos.Filename != name {
+			t.Errorf("unexpected file %s", d)
+			continue
+		}
+		matched := false
+		for i, re := range want[d.pos.Line] {
+			if re.MatchString(d.msg) {
+				want[d.pos.Line] = append(want[d.pos.Line][:i], want[d.pos.Line][i+1:]...)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			t.Errorf("unexpected diagnostic %s", d)
+		}
+	}
+	for line, res := range want {
+		for _, re := range res {
+			t.Errorf("expected diagnostic matching %q at line %d", re, line)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package calls
+
+import (
+	"errors"
+	"io"
+
+	"github.com/maxbolgarin/errm"
+)
+
+type key string
+
+func fields(id int, name string, err error) {
+	_ = errm.New("ok", "id", id, "name", name)
+	_ = errm.New("ok", errm.Int("id", id), "name", name)
+	_ = errm.New("missing", "id", id, "name")              // want `errm.New key "name" has no value`
+	_ = errm.New("bad key", 42, "id")                      // want `errm.New key 42 of type int is not a string` `errm.New key "id" has no value`
+	_ = errm.New("named key", key("id"), id)               // want `errm.New key key\("id"\) of type key is not a string` `errm.New key id of type int is not a string`
+	_ = errm.New("non-constant", name, id)                 // want `errm.New key name is not a constant`
+	_ = errm.New("duplicate", "id", id, errm.Int("id", 1)) // want `errm.New has duplicate key "id"`
+	_ = errm.Wrap(err, "wrap", "id")                       // want `errm.Wrap key "id" has no value`
+	_ = errm.T("user {id}", "id", id, "id", 1)             // want `errm.T has duplicate key "id"`
+
+	_ = errm.New("code", errm.NotFound, "id", id)
+	_ = errm.With(err, errm.NotFound, "id", id)
+	_ = errm.With(err, "id", id, "name") // want `errm.With key "name" has no value`
+
+	f := errm.NewFactory(errm.BaseFields("service", "orders", "id")) // want `errm.BaseFields key "id" has no value`
+	_ = f.With("id", id).New("factory", "name")                      // want `errm.Factory.New key "name" has no value`
+	_ = f.With(id)                                                   // want `errm.Factory.With key id of type int is not a string`
+
+	args := []any{"id", id}
+	_ = errm.New("spread", args...)
+
+	var list errm.List
+	list.New("list", "id") // want `errm.List.New key "id" has no value`
+	set := errm.NewSafeSet()
+	set.Wrap(err, "set", 1, 2) // want `errm.SafeSet.Wrap key 1 of type int is not a string` `errm.SafeSet.Wrap key 2 of type int is not a string`
+}
+
+func formats(id int, err error, format string) {
+	_ = errm.Errorf("user %d", id, "name", "bob")
+	_ = errm.Errorf("user %d in %s", id)   // want `errm.Errorf format "user %d in %s" needs 2 args, but call has 1`
+	_ = errm.Errorf("user %d", id, "name") // want `errm.Errorf key "name" has no value`
+	_ = errm.Wrapf(err, "user %[2]d", id)  // want `errm.Wrapf format "user %\[2\]d" needs 2 args, but call has 1`
+	_ = errm.Errorf("user %[0]d", id)      // want `errm.Errorf format has %d verb with a bad argument index` `errm.Errorf key id of type int is not a string`
+	_ = errm.Errorf("load: %w", io.EOF, "id", id)
+	_ = errm.Errorf("load: %w", id)    // want `errm.Errorf format %w has operand id of type int that is not an error`
+	_ = errm.New("load: %w", "id", id) // want `errm.New message has %w verb, use a function with formatting to wrap errors`
+	_ = errm.Errorf(format, id)
+}
+
+func wraps(err error) error {
+	_ = errm.Wrap(nil, "nil") // want `errm.Wrap of nil creates a new error`
+	if err == nil {
+		return errm.Wrapf(err, "wrap %d", 1) // want `errm.Wrapf of err that is nil creates a new error`
+	}
+	if err != nil {
+		return errm.Wrap(err, "wrap")
+	} else {
+		_ = errm.Wrap(err, "wrap") // want `errm.Wrap of err that is nil creates a new error`
+	}
+	if err == nil {
+		err = errors.New("reassigned")
+		return errm.Wrap(err, "wrap")
+	}
+	return errm.WrapMany([]error{nil}, "many")
+}
@@ -0,0 +1,73 @@
+package errm
+
+import (
+	"errors"
+)
+
+// Code is a machine-readable kind of an error that doesn't depend on its message, e.g. [NotFound].
+// Add a code to an error by passing it next to fields:
+//
+//	errm.New("user not found", errm.NotFound, "user_id", id)
+//	errm.With(err, errm.NotFound)
+//
+// Codes are not rendered in error messages, use [CodeOf] and [IsCode] to check them.
+type Code string
+
+// Codes of common kinds of errors, you can define your own codes.
+const (
+	Internal           Code = "internal"
+	InvalidArgument    Code = "invalid_argument"
+	NotFound           Code = "not_found"
+	AlreadyExists      Code = "already_exists"
+	PermissionDenied   Code = "permission_denied"
+	Unauthenticated    Code = "unauthenticated"
+	FailedPrecondition Code = "failed_precondition"
+	ResourceExhausted  Code = "resource_exhausted"
+	Canceled           Code = "canceled"
+	DeadlineExceeded   Code = "deadline_exceeded"
+	Unavailable        Code = "unavailable"
+	Unimplemented      Code = "unimplemented"
+)
+
+// CodeOf returns the code of the outermost layer of the error chain that has a code.
+// It looks through errors that wrap errors created by this package, e.g. using fmt.Errorf with %w.
+// It returns an empty code if there is no code in the chain.
+func CodeOf(err error) Code {
+	for err != nil {
+		e, ok := err.(errorImpl)
+		if !ok || e.top == nil {
+			if remote, ok := err.(*remoteError); ok {
+				// The code was found in causes of the original error on the sending side.
+				return remote.code
+			}
+			err = errors.Unwrap(err)
+			continue
+		}
+		for l := e.top; l != nil; l = l.next {
+			if l.code != "" {
+				return l.code
+			}
+			if l.next == nil {
+				err = l.cause
+			}
+		}
+	}
+	return ""
+}
+
+// IsCode returns true if [CodeOf] the error is one of the provided codes.
+func IsCode(err error, code Code, codes ...Code) bool {
+	errCode := CodeOf(err)
+	if errCode == "" {
+		return false
+	}
+	if errCode == code {
+		return true
+	}
+	for _, c := range codes {
+		if errCode == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package errm_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestCodeOf(t *testing.T) {
+	notFound := errm.New("user not found", errm.NotFound, "user_id", 5)
+	testCases := []struct {
+		id  string
+		err error
+		exp errm.Code
+	}{
+		{id: "nil", err: nil, exp: ""},
+		{id: "no_code", err: errm.New("x"), exp: ""},
+		{id: "external", err: io.EOF, exp: ""},
+		{id: "new", err: notFound, exp: errm.NotFound},
+		{id: "errorf", err: errm.Errorf("user %d not found", 5, errm.NotFound), exp: errm.NotFound},
+		{id: "template", err: errm.T("user {id} not found", errm.NotFound, "id", 5), exp: errm.NotFound},
+		{id: "wrap_inherits", err: errm.Wrap(notFound, "cannot load"), exp: errm.NotFound},
+		{id: "wrap_overrides", err: errm.Wrap(notFound, "cannot load", errm.Internal), exp: errm.Internal},
+		{id: "with", err: errm.With(notFound, errm.PermissionDenied), exp: errm.PermissionDenied},
+		{id: "with_external", err: errm.With(io.EOF, errm.Unavailable), exp: errm.Unavailable},
+		{id: "fmt_wrap", err: fmt.Errorf("load: %w", notFound), exp: errm.NotFound},
+		{id: "wrap_fmt_wrap", err: errm.Wrap(fmt.Errorf("load: %w", notFound), "cannot load"), exp: errm.NotFound},
+		{id: "custom", err: errm.New("x", errm.Code("quota")), exp: "quota"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if got := errm.CodeOf(tc.err); got != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+
+	if exp := "user not found user_id=5"; notFound.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, notFound)
+	}
+	if !errm.IsCode(notFound, errm.Internal, errm.NotFound) {
+		t.Errorf("expected true, got false")
+	}
+	if errm.IsCode(notFound, errm.Internal) || errm.IsCode(errm.New("x"), "") {
+		t.Errorf("expected false, got true")
+	}
+	if !errm.CompactHashIsMessageHash(notFound) {
+		t.Errorf("expected the same hash as for %q", notFound)
+	}
+}
+
+func TestKeyByCode(t *testing.T) {
+	s := errm.NewSetWithKey(errm.KeyByCode)
+	s.New("user not found", errm.NotFound, "user_id", 1)
+	s.New("order not found", errm.NotFound, "order_id", 2)
+	s.New("timeout")
+	s.New("timeout")
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+
+	if errm.Fingerprint(errm.New("x", errm.NotFound)) == errm.Fingerprint(errm.New("x", errm.Internal)) {
+		t.Errorf("expected different fingerprints for different codes")
+	}
+}
@@ -0,0 +1,281 @@
+package errm
+
+import (
+	"fmt"
+	"hash/maphash"
+)
+
+// CompactSet object is useful for collecting multiple unique errors into a single error,
+// in which error messages are separated by a ";". This object is not safe for concurrent/parallel usage.
+// It is a memory efficient alternative to [Set] for a lot of repeated errors: it keeps every unique error
+// only once and uses a 64-bit hash of the message as a key instead of the message itself.
+// The hash of an error created by this package is calculated from its messages and fields without rendering
+// the full message, so adding a duplicate doesn't allocate the message string. External errors, including
+// external wrappers of errors created by this package, are hashed and compared by their full messages.
+// Errors are the same if they have the same message, like in [Set] with the default key, hash collisions are
+// resolved by comparing errors, so different errors are never merged.
+// Errors are kept in the order of the first insertion.
+type CompactSet struct {
+	index   map[uint64]int
+	entries []compactEntry
+	seed    maphash.Seed
+	hash    func(error) uint64
+}
+
+type compactEntry struct {
+	err   error
+	count int
+	next  int // index of the next entry with the same hash or -1
+}
+
+// NewCompactSet returns a new [CompactSet] instance with an empty underlying map.
+// Working with [CompactSet] will cause allocations, use [NewCompactSetWithCapacity] if you know the number of unique errors.
+func NewCompactSet() *CompactSet {
+	return NewCompactSetWithCapacity(0)
+}
+
+// NewCompactSetWithCapacity returns a new [CompactSet] instance with an initialized underlying map.
+// It may be useful if you know the number of errors and you want to optimize code.
+func NewCompactSetWithCapacity(capacity int) *CompactSet {
+	s := &CompactSet{
+		index:   make(map[uint64]int, capacity),
+		entries: make([]compactEntry, 0, capacity),
+		seed:    maphash.MakeSeed(),
+	}
+	s.hash = s.hashError
+	return s
+}
+
+// Add sets an error to the underlying map. It is noop if you provide a nil error.
+// If there is the same error, its counter is incremented and the new error is dropped.
+func (e *CompactSet) Add(err error) {
+	if err == nil {
+		return
+	}
+	h := e.hash(err)
+	first, ok := e.index[h]
+	if !ok {
+		e.index[h] = len(e.entries)
+		e.entries = append(e.entries, compactEntry{err: err, count: 1, next: -1})
+		return
+	}
+	i := first
+	for {
+		entry := &e.entries[i]
+		if sameError(entry.err, err) {
+			entry.count++
+			return
+		}
+		if entry.next < 0 {
+			entry.next = len(e.entries)
+			e.entries = append(e.entries, compactEntry{err: err, count: 1, next: -1})
+			return
+		}
+		i = entry.next
+	}
+}
+
+// New creates an error using [New] and sets in to the underlying map.
+func (e *CompactSet) New(msg string, fields ...any) {
+	e.Add(New(msg, fields...))
+}
+
+// Errorf creates an error using [Errorf] and sets in to the underlying map.
+func (e *CompactSet) Errorf(format string, args ...any) {
+	e.Add(Errorf(format, args...))
+}
+
+// Wrap creates an error using [Wrap] and sets in to the underlying map.
+func (e *CompactSet) Wrap(err error, format string, fields ...any) {
+	e.Add(Wrap(err, format, fields...))
+}
+
+// Wrapf creates an error using [Wrapf] and sets in to the underlying map.
+func (e *CompactSet) Wrapf(err error, format string, args ...any) {
+	e.Add(Wrapf(err, format, args...))
+}
+
+// Has returns true if the [CompactSet] contains the given error.
+func (e *CompactSet) Has(err error, errs ...error) bool {
+	for _, entry := range e.entries {
+		if Is(entry.err, err, errs...) {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns a copy of errors from [CompactSet] in the insertion order.
+func (e *CompactSet) Errors() []error {
+	errs := make([]error, len(e.entries))
+	for i, entry := range e.entries {
+		errs[i] = entry.err
+	}
+	return errs
+}
+
+// Entries returns entries from [CompactSet] in the insertion order. Only [SetEntry.Err] and [SetEntry.Count]
+// are filled, [CompactSet] doesn't track time and samples to save memory.
+func (e *CompactSet) Entries() []SetEntry {
+	entries := make([]SetEntry, len(e.entries))
+	for i, entry := range e.entries {
+		entries[i] = SetEntry{Err: entry.err, Count: entry.count}
+	}
+	return entries
+}
+
+// MaxSeverity returns the maximum [Severity] of errors in the [CompactSet] or 0 if it is empty.
+func (e *CompactSet) MaxSeverity() Level {
+	var out Level
+	for _, entry := range e.entries {
+		out = max(out, Severity(entry.err))
+	}
+	return out
+}
+
+// Err returns current [CompactSet] instance as error interface or nil if it is empty.
+func (e *CompactSet) Err() error {
+	if len(e.entries) == 0 {
+		return nil
+	}
+	return compactSetError{e}
+}
+
+// Empty return true if the [CompactSet] collector is empty.
+func (e *CompactSet) Empty() bool {
+	return len(e.entries) == 0
+}
+
+// Clear removes an underlying map of errors.
+func (e *CompactSet) Clear() {
+	e.index = make(map[uint64]int)
+	e.entries = nil
+}
+
+// Len returns the number of errors in [CompactSet].
+func (e *CompactSet) Len() int {
+	return len(e.entries)
+}
+
+type compactSetError struct{ *CompactSet }
+
+func (e compactSetError) Error() string {
+	return joinEntries(e.Entries())
+}
+
+// hashError returns the same hash as maphash.String for err.Error(), but it doesn't render
+// the message of errors created by this package.
+func (e *CompactSet) hashError(err error) uint64 {
+	var h maphash.Hash
+	h.SetSeed(e.seed)
+	writeMessage(&h, err)
+	return h.Sum64()
+}
+
+// writeMessage writes the same bytes to h as err.Error() returns.
+func writeMessage(h *maphash.Hash, err error) {
+	impl, ok := err.(errorImpl)
+	if !ok || impl.top == nil {
+		_, _ = h.WriteString(err.Error())
+		return
+	}
+	var (
+		buf   [64]byte
+		empty bool
+	)
+	for l := impl.top; l != nil; l = l.next {
+		if l != impl.top {
+			_, _ = h.WriteString(": ")
+		}
+		if l.formatter != nil {
+			msg := l.render()
+			_, _ = h.WriteString(msg)
+			empty = msg == ""
+		} else {
+			_, _ = h.WriteString(l.msg)
+			empty = l.msg == ""
+			l.eachField(func(f Field) {
+				_ = h.WriteByte(' ')
+				_, _ = h.WriteString(f.Key)
+				_ = h.WriteByte('=')
+				b, str := f.render(buf[:0])
+				_, _ = h.Write(b)
+				_, _ = h.WriteString(str)
+				empty = false
+			})
+		}
+		if l.joined {
+			_, _ = h.WriteString(l.joinedCauses(!empty))
+		}
+		if l.cause != nil && !l.attached {
+			if !empty {
+				_, _ = h.WriteString(": ")
+			}
+			_, _ = h.WriteString(l.cause.Error())
+		}
+	}
+}
+
+// sameError reports whether errors have the same message. Errors created by this package are compared
+// by their messages and fields first, so the full message is rendered only if they differ.
+func sameError(a, b error) bool {
+	ai, aok := a.(errorImpl)
+	bi, bok := b.(errorImpl)
+	if aok && bok && ai.top != nil && bi.top != nil && sameLayers(ai.top, bi.top) {
+		return true
+	}
+	return a.Error() == b.Error()
+}
+
+func sameLayers(a, b *layer) bool {
+	for ; a != nil && b != nil; a, b = a.next, b.next {
+		if a == b {
+			return true
+		}
+		if a.formatter != nil || b.formatter != nil {
+			return false // formatters can't be compared, so messages are compared instead
+		}
+		if a.msg != b.msg || len(a.fields) != len(b.fields) || len(a.typed) != len(b.typed) ||
+			(a.cause == nil) != (b.cause == nil) || a.attached != b.attached {
+			return false
+		}
+		for i := range a.fields {
+			if !sameValue(a.fields[i], b.fields[i]) {
+				return false
+			}
+		}
+		for i := range a.typed {
+			if !sameField(a.typed[i], b.typed[i]) {
+				return false
+			}
+		}
+		if a.cause != nil && a.cause.Error() != b.cause.Error() {
+			return false
+		}
+		if a.joined != b.joined || a.joinedCauses(false) != b.joinedCauses(false) {
+			return false
+		}
+	}
+	return a == nil && b == nil
+}
+
+func sameValue(a, b any) bool {
+	switch v := a.(type) {
+	case string:
+		w, ok := b.(string)
+		return ok && v == w
+	case int:
+		w, ok := b.(int)
+		return ok && v == w
+	case int64:
+		w, ok := b.(int64)
+		return ok && v == w
+	case bool:
+		w, ok := b.(bool)
+		return ok && v == w
+	case Field:
+		w, ok := b.(Field)
+		return ok && sameField(v, w)
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
@@ -0,0 +1,190 @@
+package errm_test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestCompactSet(t *testing.T) {
+	s := errm.NewCompactSet()
+	if s.Err() != nil {
+		t.Errorf("expected nil, got %s", s.Err())
+	}
+
+	err := errm.New("A")
+	s.Add(err)
+	s.Add(err)
+	s.Add(errm.New("A"))
+	s.Add(errors.New("A"))
+	s.Add(nil)
+	if s.Len() != 1 {
+		t.Errorf("expected 1, got %d", s.Len())
+	}
+
+	s.Wrap(err, "B", "id", 1)
+	s.Wrap(err, "B", "id", "1")
+	s.New("B id=1: A")
+	s.Errorf("%s", "C", "id", 2)
+	if s.Len() != 3 {
+		t.Errorf("expected 3, got %d", s.Len())
+	}
+
+	exp := "A (x4); B id=1: A (x3); C id=2"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if !s.Has(err) {
+		t.Errorf("expected true, got false")
+	}
+	if !errm.Is(s.Err(), err) {
+		t.Errorf("expected true, got false")
+	}
+	if entries := s.Entries(); entries[1].Count != 3 {
+		t.Errorf("expected 3, got %d", entries[1].Count)
+	}
+
+	s.Clear()
+	if !s.Empty() {
+		t.Errorf("expected empty, got %d", s.Len())
+	}
+}
+
+func TestCompactSetCollisions(t *testing.T) {
+	s := errm.NewCompactSetWithHash(func(error) uint64 { return 42 })
+	s.New("A")
+	s.New("B")
+	s.New("A")
+	s.Add(errors.New("B"))
+	s.New("C", "id", 1)
+	s.New("C", "id", 2)
+	if s.Len() != 4 {
+		t.Errorf("expected 4, got %d", s.Len())
+	}
+	exp := "A (x2); B (x2); C id=1; C id=2"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+
+	extErr := errors.New("boom")
+	s = errm.NewCompactSetWithHash(func(error) uint64 { return 42 })
+	s.Add(errm.With(extErr, "k", 1))
+	s.Wrap(extErr, "boom", "k", 1)
+	s.Add(errm.With(extErr, "k", 1))
+	exp = "boom k=1 (x2); boom k=1: boom"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestCompactSetExternalWrappers(t *testing.T) {
+	err := errm.New("A", "id", 1)
+	s := errm.NewCompactSet()
+	s.Wrap(fmt.Errorf("ctx1: %w", err), "B")
+	s.Wrap(fmt.Errorf("ctx2: %w", err), "B")
+	s.Wrap(fmt.Errorf("ctx1: %w", err), "B")
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+	exp := "B: ctx1: A id=1 (x2); B: ctx2: A id=1"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+
+	collisions := errm.NewCompactSetWithHash(func(error) uint64 { return 42 })
+	collisions.Wrap(fmt.Errorf("ctx1: %w", err), "B")
+	collisions.Wrap(fmt.Errorf("ctx2: %w", err), "B")
+	if collisions.Len() != 2 {
+		t.Errorf("expected 2, got %d", collisions.Len())
+	}
+}
+
+func TestCompactSetHash(t *testing.T) {
+	testCases := []struct {
+		id  string
+		err error
+	}{
+		{"simple", errm.New("A")},
+		{"empty", errm.New("")},
+		{"fields", errm.New("A", "int", 1, "str", "s", "bool", true, "slice", []int{1, 2}, "uint", uint64(3))},
+		{"bad_fields", errm.New("A", 1, "b", "c")},
+		{"errorf", errm.Errorf("A %d %s", 1, "b", "c", 3.5)},
+		{"wrap", errm.Wrap(errm.New("A", "id", 1), "B", "id", 2)},
+		{"wrap_empty", errm.Wrap(errm.New("A"), "")},
+		{"wrap_external", errm.Wrap(errors.New("A"), "B", "id", 2)},
+		{"wrap_external_empty", errm.Wrap(errors.New("A"), "")},
+		{"wrapf", errm.Wrapf(errm.Wrap(errors.New("A"), "B"), "C %d", 3, "id", 4)},
+		{"wrap_external_wrapper", errm.Wrap(fmt.Errorf("C: %w", errm.New("A", "id", 1)), "B")},
+		{"external", errors.New("A")},
+	}
+	for _, test := range testCases {
+		t.Run(test.id, func(t *testing.T) {
+			if !errm.CompactHashIsMessageHash(test.err) {
+				t.Errorf("expected the same hash as for %q", test.err)
+			}
+		})
+	}
+}
+
+const (
+	benchInserts = 1_000_000
+	benchUnique  = 1_000
+)
+
+// Run with -memprofile to get memory profiles, e.g.
+//
+//	go test -run xxx -bench 'Set1M' -benchtime 1x -memprofile mem.out
+func benchmarkSet1M(b *testing.B, add func(error), keep func() int) {
+	errs := make([]error, benchUnique*10)
+	for i := range errs {
+		errs[i] = errm.New("connection refused", "host", "db-"+strconv.Itoa(i%benchUnique), "port", 5432)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		for j := 0; j < benchInserts; j++ {
+			add(errs[j%len(errs)])
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if n := keep(); n != benchUnique {
+			b.Fatalf("expected %d, got %d", benchUnique, n)
+		}
+		b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "retained-B")
+	}
+}
+
+func BenchmarkSet1M(b *testing.B) {
+	var s *errm.Set
+	benchmarkSet1M(b, func(err error) {
+		if s == nil {
+			s = errm.NewSet()
+		}
+		s.Add(err)
+	}, func() int {
+		n := s.Len()
+		s = nil
+		return n
+	})
+}
+
+func BenchmarkCompactSet1M(b *testing.B) {
+	var s *errm.CompactSet
+	benchmarkSet1M(b, func(err error) {
+		if s == nil {
+			s = errm.NewCompactSet()
+		}
+		s.Add(err)
+	}, func() int {
+		n := s.Len()
+		s = nil
+		return n
+	})
+}
@@ -2,24 +2,166 @@
 package errm
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
+	"github.com/maxbolgarin/errm/internal/fmtscan"
 	"github.com/rotisserie/eris"
 )
 
 type errorImpl struct {
 	err error
+	top *layer
 }
 
-func newError(err error) errorImpl {
-	return errorImpl{err: err}
+// layer keeps the structured data of a single message of an error chain, it is immutable after creation.
+// Every [Wrap] creates a new layer that points to the layers of the wrapped error.
+type layer struct {
+	msg       string // message without fields
+	format    string // raw format or template of the message, it is empty if the message is not formatted
+	fields    []any
+	typed     []Field // fields of [NewFields] and [WrapFields], they are kept without boxing into interfaces
+	inline    []any   // fields interpolated into the template by [T]
+	next      *layer  // wrapped layer, it is nil for the root layer
+	cause     error   // external error wrapped by the root layer
+	causes    []error
+	joined    bool // causes are rendered after the message, see [WrapMany]
+	code      Code
+	formatter Formatter // it renders the message with fields if it is set, see [MessageFormatter]
+	// attached is true for layers that add fields to a message of an external error using [With],
+	// the message is the message of the cause, so the cause is not rendered again.
+	attached bool
+	public   string    // user-safe message, see [WithPublic]
+	retry    retryMark // see [MarkRetryable] and [MarkPermanent]
+	severity Level     // see [WithSeverity]
+	// origin is the layer this one was copied from by [annotate], it keeps the identity of the error,
+	// see [KeyByRootCause].
+	origin *layer
 }
 
-// Error implements error interface, it just returns error message with applied fields in field=val format.
+func newError(err error, l *layer) errorImpl {
+	for _, f := range l.fields {
+		if code, ok := f.(Code); ok {
+			l.code = code
+		}
+	}
+	return errorImpl{err: err, top: l}
+}
+
+// wrapLayer returns a new layer on top of the layers of err.
+func wrapLayer(err error, msg string, fields []any) *layer {
+	l := &layer{msg: msg, fields: fields}
+	if inner, ok := err.(errorImpl); ok && inner.top != nil {
+		l.next = inner.top
+	} else {
+		l.cause = err
+	}
+	return l
+}
+
+// joinedCauses returns messages of causes of [WrapMany] in a ": [cause1; cause2]" format.
+func (l *layer) joinedCauses(withSep bool) string {
+	if !l.joined {
+		return ""
+	}
+	b := make([]byte, 0, len(l.causes)*fieldAverageLength*4)
+	if withSep {
+		b = append(b, ':', ' ')
+	}
+	b = append(b, '[')
+	for i, err := range l.causes {
+		if i > 0 {
+			b = append(b, ';', ' ')
+		}
+		b = append(b, err.Error()...)
+	}
+	return string(append(b, ']'))
+}
+
+// render returns the message with fields.
+func (l *layer) render() string {
+	if l.formatter != nil {
+		return l.formatter(l.msg, append(fieldList(l.fields), l.typed...))
+	}
+	return buildErrorMessage(l.msg, l.fields, l.typed...)
+}
+
+// eachField calls fn for fields of the layer: pairs first and then typed fields.
+func (l *layer) eachField(fn func(f Field)) {
+	forEachField(l.fields, fn)
+	for _, f := range l.typed {
+		fn(f)
+	}
+}
+
+// template returns the message before formatting.
+func (l *layer) template() string {
+	if l.format != "" {
+		return l.format
+	}
+	return l.msg
+}
+
+// root returns the last layer in the chain.
+func (l *layer) root() *layer {
+	for l.next != nil {
+		l = l.next
+	}
+	return l
+}
+
+// Error implements error interface, it returns messages of all layers with applied fields in field=val format.
 func (e errorImpl) Error() string {
-	return e.err.Error()
+	if e.top == nil {
+		return e.err.Error()
+	}
+	out := strings.Builder{}
+	var (
+		buf   [64]byte
+		empty bool
+	)
+	for l := e.top; l != nil; l = l.next {
+		if l != e.top {
+			out.WriteString(": ")
+		}
+		if l.formatter != nil {
+			msg := l.render()
+			out.WriteString(msg)
+			empty = msg == ""
+		} else {
+			out.WriteString(l.msg)
+			empty = l.msg == ""
+			l.eachField(func(f Field) {
+				out.WriteByte(' ')
+				out.WriteString(f.Key)
+				out.WriteByte('=')
+				b, s := f.render(buf[:0])
+				out.Write(b)
+				out.WriteString(s)
+				empty = false
+			})
+		}
+		if l.joined {
+			out.WriteString(l.joinedCauses(!empty))
+		}
+		if l.cause != nil && !l.attached {
+			if !empty {
+				out.WriteString(": ")
+			}
+			out.WriteString(l.cause.Error())
+		}
+	}
+	return out.String()
+}
+
+// Unwrap returns the underlying error chain and errors that were attached using %w verbs in [Errorf] and [Wrapf]
+// or using [WrapMany], so [errors.Is] and [errors.As] can find them.
+func (e errorImpl) Unwrap() []error {
+	return append([]error{e.err}, causes(e)...)
 }
 
 // String is a wrapper of Error method.
@@ -29,7 +171,7 @@ func (e errorImpl) String() string {
 
 // StackForLogger returns slice ["stack", "[...]"] that can be used as fields for logger if you want to log stack trace.
 func (e errorImpl) StackForLogger() []any {
-	jsonErr := ToJSON(e.err)
+	jsonErr := eris.ToJSON(e.err, true)
 	root, ok := jsonErr["root"].(map[string]any)
 	if !ok {
 		return nil
@@ -37,58 +179,154 @@ func (e errorImpl) StackForLogger() []any {
 	return []any{"stack", root["stack"]}
 }
 
-// Format is used to handle %+v in formatted print, that will print stack trace.
+// Format is used to handle %+v in formatted print, that will print the message and the stack trace.
+// Frames of runtime and this package are skipped like in [Encode].
 func (e errorImpl) Format(s fmt.State, verb rune) {
-	var withTrace bool
-	switch verb {
-	case 'v':
-		if s.Flag('+') {
-			withTrace = true
+	_, _ = io.WriteString(s, e.Error())
+	if verb != 'v' || !s.Flag('+') {
+		return
+	}
+	for _, frame := range eris.Unpack(e.err).ErrRoot.Stack {
+		if isInternalFrame(frame.Name) {
+			continue
 		}
-	default:
-		break
+		_, _ = fmt.Fprintf(s, "\n\t%s:%s:%d", frame.Name, frame.File, frame.Line)
 	}
-	str := eris.ToString(e.err, withTrace)
-	_, _ = io.WriteString(s, str)
 }
 
 // New creates a new error with a static message and pairs of fields in a field=val format.
 func New(msg string, fields ...any) error {
-	return newError(eris.New(buildErrorMessage(msg, fields)))
+	l := defaultOptions.layer(nil, msg, fields)
+	return newError(eris.New(l.render()), l)
+}
+
+// With adds pairs of fields in a field=val format to the error without adding a new message like [Wrap] does:
+//
+//	errm.With(errm.New("not found", "id", 1), "user_id", 5)
+//	// not found id=1 user_id=5
+//
+// Fields are added to the outermost message of the error. A [Code] in the fields replaces the code of the error.
+// It returns nil if you provide a nil error.
+func With(err error, fields ...any) error {
+	if err == nil || len(fields) == 0 {
+		return err
+	}
+	return annotate(err, func(l *layer) {
+		l.fields = slices.Clip(l.fields)
+		for _, f := range l.typed {
+			l.fields = append(l.fields, f) // typed fields are moved to keep the order of fields
+		}
+		l.typed = nil
+		l.fields = append(l.fields, fields...)
+	})
+}
+
+// annotate returns the error with a copy of its outermost layer changed by fn.
+// External errors get a new layer that has their message, see [With].
+func annotate(err error, fn func(l *layer)) error {
+	if e, ok := err.(errorImpl); ok && e.top != nil {
+		l := *e.top
+		if l.origin == nil {
+			l.origin = e.top
+		}
+		fn(&l)
+		return newError(e.err, &l)
+	}
+	l := &layer{msg: err.Error(), cause: err, attached: true}
+	fn(l)
+	return newError(eris.Wrap(err, ""), l)
+}
+
+// WrapMany creates a new error with a static message and pairs of fields in a field=val format that has several causes,
+// it is useful for fan-out operations that have several failures in the same context:
+//
+//	errm.WrapMany([]error{errA, errB}, "cannot replicate", "key", key)
+//	// cannot replicate key=abc: [region eu: timeout; region us: connection refused]
+//
+// Causes can be found using [Is] and [As]. Nil causes are skipped, it works like [New] if there are no causes.
+func WrapMany(causes []error, msg string, fields ...any) error {
+	nonNil := make([]error, 0, len(causes))
+	for _, err := range causes {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+	l := &layer{msg: msg, fields: fields}
+	if len(nonNil) == 0 {
+		return newError(eris.New(l.render()), l)
+	}
+	l.causes, l.joined = nonNil, true
+	rendered := l.render()
+	return newError(eris.New(rendered+l.joinedCauses(rendered != "")), l)
 }
 
 // Errorf creates a new error with a formatted message and pairs of fields in a field=val format.
+// Arguments that are not used by the format are fields, the format is parsed following the rules of the fmt package.
+// Like [fmt.Errorf], it supports one or more %w verbs, their operands can be found using [Is] and [As].
 func Errorf(msg string, args ...any) error {
 	args, fields := separateArgsAndFields(msg, args)
-	if len(args) == 0 {
-		return New(msg, fields...)
-	}
-	return newError(eris.Errorf(buildErrorMessage(msg, fields), args...))
+	l := defaultOptions.formatLayer(nil, msg, args, fields)
+	return newError(eris.New(l.render()), l)
 }
 
 // Wrap adds additional context to all error types while maintaining the type of the original error;
 // It also adds pairs of fields in a field=val format to message.
 func Wrap(err error, msg string, fields ...any) error {
+	l := defaultOptions.layer(err, msg, fields)
 	if err == nil {
-		return New(msg, fields...)
+		return newError(eris.New(l.render()), l)
 	}
-	return newError(eris.Wrap(unwrap(err), buildErrorMessage(msg, fields)))
+	return newError(eris.Wrap(unwrap(err), l.render()), l)
 }
 
 // Wrapf adds additional context to all error types while maintaining the type of the original error;
 // It waits for formatted input and also adds pairs of fields in a field=val format to message.
+// Arguments that are not used by the format are fields, the format is parsed following the rules of the fmt package.
+// Like [fmt.Errorf], it supports one or more %w verbs, their operands can be found using [Is] and [As].
 func Wrapf(err error, msg string, args ...any) error {
+	args, fields := separateArgsAndFields(msg, args)
+	l := defaultOptions.formatLayer(err, msg, args, fields)
 	if err == nil {
-		return Errorf(msg, args...)
+		return newError(eris.New(l.render()), l)
 	}
-	args, fields := separateArgsAndFields(msg, args)
-	if len(args) == 0 {
-		return Wrap(err, msg, args...)
+	return newError(eris.Wrap(unwrap(err), l.render()), l)
+}
+
+// options define how errors are created, package functions use default options and [Factory] sets its own.
+// Error chains are created by exported functions, so stack traces don't have frames of helpers.
+type options struct {
+	noStack   bool
+	formatter Formatter
+}
+
+var defaultOptions options
+
+// layer returns a new layer with a static message, it is on top of the layers of err if err is not nil.
+func (o *options) layer(err error, msg string, fields []any) *layer {
+	var l *layer
+	if err == nil {
+		l = &layer{msg: msg, fields: fields}
+	} else {
+		l = wrapLayer(err, msg, fields)
+	}
+	l.formatter = o.formatter
+	return l
+}
+
+// formatLayer returns a new layer with a formatted message, see [options.layer].
+func (o *options) formatLayer(err error, msg string, args, fields []any) *layer {
+	if len(args) == 0 && !strings.Contains(msg, "%") {
+		return o.layer(err, msg, fields)
 	}
-	return newError(eris.Wrapf(unwrap(err), buildErrorMessage(msg, fields), args...))
+	format, wrapped := wrapVerbs(msg, args)
+	l := o.layer(err, fmt.Sprintf(format, args...), fields)
+	l.format = msg
+	l.causes = wrapped
+	return l
 }
 
 // Is reports whether any error in err's chain matches target.
+// The chain includes errors attached using %w verbs in [Errorf] and [Wrapf] and causes of [WrapMany].
 func Is(err, target error, targets ...error) bool {
 	var set setError
 	if eris.As(err, &set) {
@@ -98,36 +336,80 @@ func Is(err, target error, targets ...error) bool {
 	if eris.As(err, &list) {
 		return list.Has(target, targets...)
 	}
+	var compact compactSetError
+	if eris.As(err, &compact) {
+		return compact.Has(target, targets...)
+	}
+
+	if is(err, target) {
+		return true
+	}
+	for _, t := range targets {
+		if is(err, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func is(err, target error) bool {
+	if eris.Is(unwrap(err), unwrap(target)) {
+		return true
+	}
+	if e, ok := asError(err); ok {
+		for _, cause := range causes(e) {
+			if Is(cause, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-	res := eris.Is(unwrap(err), unwrap(target))
-	if !res && len(targets) > 0 {
-		for _, t := range targets {
-			if eris.Is(unwrap(err), unwrap(t)) {
+// As finds the first error in err's chain that matches target, and if one is found, sets target to that error value.
+// The chain includes errors attached using %w verbs in [Errorf] and [Wrapf], causes of [WrapMany]
+// and errors of [List] and [Set].
+// It panics if target is not a non-nil pointer to either a type that implements error, or to any interface type.
+func As(err error, target any) bool {
+	if errs, ok := collectorErrors(err); ok {
+		for _, err := range errs {
+			if As(err, target) {
 				return true
 			}
 		}
+		return false
 	}
-	return res
+	return errors.As(err, target)
 }
 
-// Contains reports whether any error in err's chain contains target string.
+// Contains reports whether the message of err or any error in its chain contains target string.
+// The message includes fields, so they can be found too.
 func Contains(err error, target string) bool {
-	return err != nil && strings.Contains(eris.ToString(unwrap(err), false), target)
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), target) || strings.Contains(eris.ToString(unwrap(err), false), target)
 }
 
-// ContainsErr reports whether any error in err's chain contains target string representation.
+// ContainsErr reports whether the message of err or any error in its chain contains the message of target.
 func ContainsErr(err, target error) bool {
-	return target != nil && Contains(err, eris.ToString(unwrap(target), false))
+	return target != nil && Contains(err, target.Error())
 }
 
-// ToJSON returns a JSON formatted map for a given error.
-func ToJSON(err error) map[string]any {
-	return eris.ToJSON(unwrap(err), true)
+// ToJSON returns the error in the JSON schema of [Encode] as a map, it returns nil for a nil error.
+func ToJSON(err error, opts ...JSONOption) map[string]any {
+	data, encodeErr := Encode(err, opts...)
+	if encodeErr != nil {
+		return nil
+	}
+	var out map[string]any
+	_ = json.Unmarshal(data, &out)
+	return out
 }
 
 // StackForLogger returns slice ["stack", "[...]"] that can be used as fields for logger if you want to log stack trace.
 func StackForLogger(err error) []any {
-	jsonErr := ToJSON(err)
+	jsonErr := eris.ToJSON(unwrap(err), true)
 	root, ok := jsonErr["root"].(map[string]any)
 	if !ok {
 		return nil
@@ -148,39 +430,68 @@ func unwrap(err error) error {
 	return err
 }
 
+// causes returns errors attached using %w verbs or [WrapMany] to all layers of the error.
+func causes(e errorImpl) []error {
+	var out []error
+	for l := e.top; l != nil; l = l.next {
+		out = append(out, l.causes...)
+	}
+	return out
+}
+
+// asError finds an error created by this package in err's chain.
+func asError(err error) (errorImpl, bool) {
+	var errObject errorImpl
+	if err == nil || !eris.As(err, &errObject) || errObject.top == nil {
+		return errorImpl{}, false
+	}
+	return errObject, true
+}
+
 var fieldAverageLength = 8
 
-func buildErrorMessage(baseErr string, fields []any) string {
-	if len(fields) < 2 {
+func buildErrorMessage(baseErr string, fields []any, typed ...Field) string {
+	if len(fields) == 0 && len(typed) == 0 {
 		return baseErr
 	}
+	size := len(baseErr) + (len(fields)+2*len(typed))*(fieldAverageLength+1)
+	for _, f := range fields {
+		if _, ok := f.(Field); ok {
+			size += fieldAverageLength + 1 // it takes one element instead of two
+		}
+	}
 	out := strings.Builder{}
-	out.Grow(len(baseErr) + len(fields)*(fieldAverageLength+1))
+	out.Grow(size)
 	out.WriteString(baseErr)
 
-	for i := 0; i < len(fields); i += 2 {
-		if len(fields) <= i+1 {
-			break
-		}
-		key, ok := fields[i].(string)
-		if !ok {
-			continue
-		}
+	var buf [64]byte
+	write := func(f Field) {
 		out.WriteRune(' ')
-		out.WriteString(key)
+		out.WriteString(f.Key)
 		out.WriteRune('=')
-		out.WriteString(fmt.Sprint(fields[i+1]))
+		b, s := f.render(buf[:0])
+		out.Write(b)
+		out.WriteString(s)
+	}
+	forEachField(fields, write)
+	for _, f := range typed {
+		write(f)
 	}
 
 	return out.String()
 }
 
+// separateArgsAndFields returns operands of the format and fields after them.
+// If there are not enough operands, fmt renders missing ones as %!v(MISSING), it panics in the strict mode.
 func separateArgsAndFields(msg string, args []any) ([]any, []any) {
 	var fields []any
-	numberOfFormats := strings.Count(msg, "%")
+	_, numberOfFormats := fmtscan.Scan(msg)
 	if numberOfFormats == 0 {
 		return nil, args
 	}
+	if numberOfFormats > len(args) && strict.Load() {
+		panic(fmt.Sprintf("errm: format %q needs %d arguments, got %d", msg, numberOfFormats, len(args)))
+	}
 	if numberOfFormats <= len(args) {
 		fields = args[numberOfFormats:]
 		args = args[:numberOfFormats]
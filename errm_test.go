@@ -3,7 +3,11 @@ package errm_test
 import (
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/maxbolgarin/errm"
 )
@@ -32,7 +36,7 @@ func TestNew(t *testing.T) {
 		{
 			id:  "many_fields",
 			err: errm.New("some-err", "field", "value", "field2", []any{123, 321}, "field3", 123, "field4"),
-			exp: "some-err field=value field2=[123 321] field3=123",
+			exp: "some-err field=value field2=[123 321] field3=123 field4=!MISSING",
 		},
 	}
 
@@ -69,12 +73,12 @@ func TestErrorf(t *testing.T) {
 		{
 			id:  "format_many_fields",
 			err: errm.Errorf("some-err", "field", "value", "field2", []any{123, 321}, "field3", 123, "field4"),
-			exp: "some-err field=value field2=[123 321] field3=123",
+			exp: "some-err field=value field2=[123 321] field3=123 field4=!MISSING",
 		},
 		{
 			id:  "format_many_fields_2",
 			err: errm.Errorf("some-err %s %d", "a", 1, "field", "value", "field2", []any{123, 321}, "field3", 123, "field4"),
-			exp: "some-err a 1 field=value field2=[123 321] field3=123",
+			exp: "some-err a 1 field=value field2=[123 321] field3=123 field4=!MISSING",
 		},
 	}
 
@@ -89,19 +93,19 @@ func TestErrorf(t *testing.T) {
 
 func TestWrap(t *testing.T) {
 	err := errm.Errorf("some-err %s %d", "a", 1, "field", "value", "field2", []any{123, 321}, "field3", 123, "field4")
-	exp := "some-err a 1 field=value field2=[123 321] field3=123"
+	exp := "some-err a 1 field=value field2=[123 321] field3=123 field4=!MISSING"
 	if err.Error() != exp {
 		t.Errorf("expected %s, got %s", exp, err)
 	}
 
 	err = errm.Wrap(err, "second error", "field", 123, "testtt", "122", "asd")
-	exp = "second error field=123 testtt=122: " + exp
+	exp = "second error field=123 testtt=122 asd=!MISSING: " + exp
 	if err.Error() != exp {
 		t.Errorf("expected %s, got %s", exp, err)
 	}
 
 	err = errm.Wrapf(err, "third error with %s", "format", "v", "a", "testtt")
-	exp = "third error with format v=a: " + exp
+	exp = "third error with format v=a testtt=!MISSING: " + exp
 	if err.Error() != exp {
 		t.Errorf("expected %s, got %s", exp, err)
 	}
@@ -137,12 +141,12 @@ func TestIs(t *testing.T) {
 		t.Errorf("expected true, got %t", errm.Is(f4(), base))
 	}
 
-	// Errorf doesn't wrap in eris because it is using Sprintf
+	// Errorf attaches %w operands like fmt.Errorf does
 	f5 := func() error {
 		return errm.Errorf("second error: %w", base)
 	}
-	if errm.Is(f5(), base) {
-		t.Errorf("expected false, got %t", errm.Is(f5(), base))
+	if !errm.Is(f5(), base) {
+		t.Errorf("expected true, got %t", errm.Is(f5(), base))
 	}
 
 	wrapped := errm.Wrap(base, "some-error")
@@ -390,8 +394,8 @@ func TestContains(t *testing.T) {
 	if !errm.Contains(err, "field3") {
 		t.Errorf("expected true, got %t", errm.Contains(err, "field3"))
 	}
-	if errm.Contains(err, "field4") {
-		t.Errorf("expected false, got %t", errm.Contains(err, "field4"))
+	if !errm.Contains(err, "field4=!MISSING") {
+		t.Errorf("expected true, got %t", errm.Contains(err, "field4=!MISSING"))
 	}
 	if errm.Contains(err, "another-err") {
 		t.Errorf("expected false, got %t", errm.Contains(err, "another-err"))
@@ -404,12 +408,24 @@ func TestContains(t *testing.T) {
 	if !errm.Contains(anotherErr, "field3") {
 		t.Errorf("expected true, got %t", errm.Contains(anotherErr, "field3"))
 	}
-	if errm.Contains(anotherErr, "field4") {
-		t.Errorf("expected false, got %t", errm.Contains(anotherErr, "field4"))
+	if !errm.Contains(anotherErr, "field4=!MISSING") {
+		t.Errorf("expected true, got %t", errm.Contains(anotherErr, "field4=!MISSING"))
 	}
 	if !errm.Contains(anotherErr, "another-err") {
 		t.Errorf("expected true, got %t", errm.Contains(anotherErr, "another-err"))
 	}
+
+	withErr := errm.With(errm.New("not found", "id", 1), "user_id", 5)
+	if !errm.Contains(withErr, "user_id=5") {
+		t.Errorf("expected true, got %t", errm.Contains(withErr, "user_id=5"))
+	}
+	withExt := errm.With(io.EOF, "k", 1)
+	if !errm.Contains(withExt, "k=1") {
+		t.Errorf("expected true, got %t", errm.Contains(withExt, "k=1"))
+	}
+	if !errm.Contains(withExt, "EOF") {
+		t.Errorf("expected true, got %t", errm.Contains(withExt, "EOF"))
+	}
 }
 
 func TestContainsErr(t *testing.T) {
@@ -421,8 +437,8 @@ func TestContainsErr(t *testing.T) {
 	if !errm.ContainsErr(err, errors.New("field3")) {
 		t.Errorf("expected true, got %t", errm.ContainsErr(err, errors.New("field3")))
 	}
-	if errm.ContainsErr(err, fmt.Errorf("field4")) {
-		t.Errorf("expected false, got %t", errm.ContainsErr(err, fmt.Errorf("field4")))
+	if errm.ContainsErr(err, fmt.Errorf("field5")) {
+		t.Errorf("expected false, got %t", errm.ContainsErr(err, fmt.Errorf("field5")))
 	}
 
 	anotherErr := errm.Wrap(err, "another-err")
@@ -436,8 +452,8 @@ func TestContainsErr(t *testing.T) {
 	if !errm.ContainsErr(anotherErr, errors.New("field3")) {
 		t.Errorf("expected true, got %t", errm.ContainsErr(anotherErr, errors.New("field3")))
 	}
-	if errm.ContainsErr(anotherErr, fmt.Errorf("field4")) {
-		t.Errorf("expected false, got %t", errm.ContainsErr(anotherErr, fmt.Errorf("field4")))
+	if errm.ContainsErr(anotherErr, fmt.Errorf("field5")) {
+		t.Errorf("expected false, got %t", errm.ContainsErr(anotherErr, fmt.Errorf("field5")))
 	}
 	if !errm.ContainsErr(anotherErr, anotherErr) {
 		t.Errorf("expected true, got %t", errm.ContainsErr(anotherErr, anotherErr))
@@ -539,3 +555,263 @@ func TestList(t *testing.T) {
 		t.Errorf("expected 3, got %d", s.Len())
 	}
 }
+
+func TestSetOrder(t *testing.T) {
+	s := errm.NewSet()
+	s.New("C")
+	s.New("A")
+	s.New("B")
+	s.New("A", "field", 1)
+	s.New("C")
+
+	exp := "C (x2); A; B; A field=1"
+	for i := 0; i < 10; i++ {
+		if got := s.Err().Error(); got != exp {
+			t.Fatalf("expected %s, got %s", exp, got)
+		}
+	}
+
+	errs := s.Errors()
+	if len(errs) != 4 {
+		t.Fatalf("expected 4, got %d", len(errs))
+	}
+	if errs[0].Error() != "C" || errs[3].Error() != "A field=1" {
+		t.Errorf("expected insertion order, got %v", errs)
+	}
+
+	sorted := errm.NewSafeSet(errm.SortedOrder())
+	sorted.New("C")
+	sorted.New("A")
+	sorted.New("B")
+	sorted.New("A", "field", 1)
+	sorted.New("C")
+
+	exp = "A; A field=1; B; C (x2)"
+	if got := sorted.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if errs := sorted.Errors(); errs[0].Error() != "A" || errs[3].Error() != "C" {
+		t.Errorf("expected sorted order, got %v", errs)
+	}
+}
+
+func TestSetEntries(t *testing.T) {
+	s := errm.NewSet(errm.KeepSamples(2))
+	for i := 0; i < 42; i++ {
+		s.New("connection refused", "host", "db")
+	}
+	s.New("timeout")
+
+	exp := "connection refused host=db (x42); timeout"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+
+	entries := s.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2, got %d", len(entries))
+	}
+	if entries[0].Count != 42 || entries[1].Count != 1 {
+		t.Errorf("expected 42 and 1, got %d and %d", entries[0].Count, entries[1].Count)
+	}
+	if len(entries[0].Samples) != 2 || len(entries[1].Samples) != 1 {
+		t.Errorf("expected 2 and 1 samples, got %d and %d", len(entries[0].Samples), len(entries[1].Samples))
+	}
+	if entries[0].FirstSeen.After(entries[0].LastSeen) || entries[0].LastSeen.After(entries[1].FirstSeen) {
+		t.Errorf("expected ordered timestamps, got %v", entries)
+	}
+	if entries[0].String() != "connection refused host=db (x42)" {
+		t.Errorf("expected connection refused host=db (x42), got %s", entries[0])
+	}
+
+	s = errm.NewSet()
+	s.New("A")
+	s.New("A")
+	if entries := s.Entries(); entries[0].Samples != nil {
+		t.Errorf("expected no samples, got %v", entries[0].Samples)
+	}
+
+	var ticks int64
+	s = errm.NewSet(errm.Clock(func() time.Time {
+		ticks++
+		return time.Unix(ticks, 0)
+	}))
+	s.New("A")
+	s.New("A")
+	if entries := s.Entries(); entries[0].FirstSeen.Unix() != 1 || entries[0].LastSeen.Unix() != 2 {
+		t.Errorf("expected 1 and 2, got %v and %v", entries[0].FirstSeen, entries[0].LastSeen)
+	}
+
+	s = errm.NewSet(errm.Clock(nil))
+	s.New("A")
+	s.New("A")
+	if entries := s.Entries(); entries[0].Count != 2 || !entries[0].FirstSeen.IsZero() || !entries[0].LastSeen.IsZero() {
+		t.Errorf("expected 2 and no timestamps, got %v", entries[0])
+	}
+}
+
+type customError struct {
+	code int
+}
+
+func (e *customError) Error() string {
+	return "custom " + fmt.Sprint(e.code)
+}
+
+func TestErrorfWrapVerb(t *testing.T) {
+	base := errors.New("base")
+	custom := &customError{code: 42}
+	sentinel := errm.New("sentinel")
+
+	err := errm.Errorf("load %s: %w", "config", base, "path", "/etc/app")
+	if exp := "load config: base path=/etc/app"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if !errm.Is(err, base) || !errors.Is(err, base) {
+		t.Errorf("expected true, got false")
+	}
+
+	err = errm.Errorf("%w and %w", custom, sentinel)
+	if exp := "custom 42 and sentinel"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if !errm.Is(err, custom) || !errm.Is(err, sentinel) || !errors.Is(err, custom) || !errors.Is(err, sentinel) {
+		t.Errorf("expected true, got false")
+	}
+	var target *customError
+	if !errm.As(err, &target) || target.code != 42 {
+		t.Errorf("expected custom error, got %v", target)
+	}
+	target = nil
+	if !errors.As(errm.Wrap(err, "outer"), &target) || target.code != 42 {
+		t.Errorf("expected custom error, got %v", target)
+	}
+
+	err = errm.Wrapf(errm.New("inner"), "wrap %[2]s %[1]w", custom, "x", "id", 1)
+	if exp := "wrap x custom 42 id=1: inner"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if !errm.Is(errm.Wrap(err, "outer"), custom) {
+		t.Errorf("expected true, got false")
+	}
+
+	err = errm.Errorf("not error %w", 42)
+	if exp := "not error %!w(int=42)"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+
+	list := errm.NewList()
+	list.Add(errm.New("A"))
+	list.Errorf("B: %w", custom)
+	target = nil
+	if !errm.As(list.Err(), &target) || target.code != 42 {
+		t.Errorf("expected custom error, got %v", target)
+	}
+	if !errm.Is(list.Err(), custom) {
+		t.Errorf("expected true, got false")
+	}
+}
+
+func TestWrapMany(t *testing.T) {
+	errEU := errm.New("timeout", "region", "eu")
+	errUS := errors.New("connection refused")
+	custom := &customError{code: 7}
+
+	err := errm.WrapMany([]error{errEU, nil, errUS, custom}, "cannot replicate", "key", "abc")
+	exp := "cannot replicate key=abc: [timeout region=eu; connection refused; custom 7]"
+	if err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	for _, target := range []error{errEU, errUS, custom} {
+		if !errm.Is(err, target) || !errors.Is(err, target) {
+			t.Errorf("expected %s to be found", target)
+		}
+	}
+	var target *customError
+	if !errm.As(err, &target) || target.code != 7 {
+		t.Errorf("expected custom error, got %v", target)
+	}
+
+	wrapped := errm.Wrap(err, "sync")
+	if exp := "sync: " + exp; wrapped.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, wrapped)
+	}
+	if !errm.Is(wrapped, errUS) {
+		t.Errorf("expected true, got false")
+	}
+
+	if got := errm.WrapMany([]error{errUS}, "").Error(); got != "[connection refused]" {
+		t.Errorf("expected [connection refused], got %s", got)
+	}
+	if got := errm.WrapMany(nil, "nothing", "key", 1).Error(); got != "nothing key=1" {
+		t.Errorf("expected nothing key=1, got %s", got)
+	}
+
+	s := errm.NewCompactSetWithHash(func(error) uint64 { return 1 })
+	s.Add(errm.WrapMany([]error{errEU}, "cannot replicate"))
+	s.Add(errm.WrapMany([]error{errUS}, "cannot replicate"))
+	s.Add(errm.WrapMany([]error{errUS}, "cannot replicate"))
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+	if !errm.CompactHashIsMessageHash(err) {
+		t.Errorf("expected the same hash as for %q", err)
+	}
+	if got := errm.KeyByTemplate(err); got != "cannot replicate key=: [timeout region=; connection refused; custom 7]" {
+		t.Errorf("unexpected key %s", got)
+	}
+}
+
+func TestWith(t *testing.T) {
+	base := errm.New("not found", "id", 1)
+	err := errm.With(base, "user_id", 5)
+	if exp := "not found id=1 user_id=5"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if exp := "not found id=1"; base.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, base)
+	}
+	if !errm.Is(err, base) || !errors.Is(err, base) {
+		t.Errorf("expected true, got false")
+	}
+	other := errm.With(err, "table", "users")
+	_ = errm.With(err, "shard", 3)
+	if exp := "not found id=1 user_id=5 table=users"; other.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, other)
+	}
+	if exp := []any{"id", 1, "user_id", 5, "table", "users"}; !reflect.DeepEqual(errm.Fields(other), exp) {
+		t.Errorf("expected %v, got %v", exp, errm.Fields(other))
+	}
+
+	wrapped := errm.With(errm.Wrap(base, "cannot load"), "user_id", 5)
+	if exp := "cannot load user_id=5: not found id=1"; wrapped.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, wrapped)
+	}
+	if exp := "cannot load user_id=5: not found id=1\n\t"; !strings.HasPrefix(fmt.Sprintf("%+v", wrapped), exp) {
+		t.Errorf("expected prefix %q, got %q", exp, fmt.Sprintf("%+v", wrapped))
+	}
+
+	external := errm.With(io.EOF, "path", "a.txt")
+	if exp := "EOF path=a.txt"; external.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, external)
+	}
+	if !errors.Is(external, io.EOF) || !errm.Is(external, io.EOF) {
+		t.Errorf("expected true, got false")
+	}
+	if exp := "cannot read: EOF path=a.txt"; errm.Wrap(external, "cannot read").Error() != exp {
+		t.Errorf("expected %s, got %s", exp, errm.Wrap(external, "cannot read"))
+	}
+
+	for _, err := range []error{err, other, wrapped, external} {
+		if !errm.CompactHashIsMessageHash(err) {
+			t.Errorf("expected the same hash as for %q", err)
+		}
+	}
+
+	if errm.With(nil, "user_id", 5) != nil {
+		t.Errorf("expected nil")
+	}
+	if errm.With(io.EOF) != io.EOF {
+		t.Errorf("expected the same error")
+	}
+}
@@ -0,0 +1,207 @@
+package errmhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+)
+
+// maxProblemSize is the maximum size of a response body that is read by [FromResponse].
+const maxProblemSize = 64 << 10
+
+// FromResponse returns an error for a response with a non-2xx status or nil for other responses.
+// The error has "method", "url" and "status" fields, e.g.
+//
+//	request failed method=GET url=https://api.example.com/users/42?token=xxxxx status=404: user not found user_id=42
+//
+// The user info of the URL is removed and values of its query are masked, so credentials are not logged.
+// If the body is an RFC 9457 application/problem+json object, the cause is built from its detail or title,
+// extension members are added as fields and the "code" member is used as the code of the error.
+// Otherwise the code is chosen by the status using [CodeFromStatus], so [errm.IsCode] works with remote services.
+// It reads and closes the body of an error response.
+func FromResponse(resp *http.Response) error {
+	if resp == nil || (resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return nil
+	}
+
+	var fields []any
+	if req := resp.Request; req != nil {
+		fields = append(fields, "method", req.Method)
+		if req.URL != nil {
+			fields = append(fields, "url", redactURL(req.URL))
+		}
+	}
+	fields = append(fields, "status", resp.StatusCode)
+
+	cause := problemError(resp)
+	if cause == nil {
+		return errm.New("request failed", append(fields, CodeFromStatus(resp.StatusCode))...)
+	}
+	return errm.Wrap(cause, "request failed", fields...)
+}
+
+// Do sends the request using the client and returns an error for a response with a non-2xx status,
+// see [FromResponse]. The error has a "duration" field, the body of such response is closed.
+// Use [Transport] in the client to get errors of the errm package for failed requests.
+// The URL in the [url.Error] of a failed request is redacted like in [FromResponse].
+// The default client is used if the client is nil.
+func Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			u, parseErr := url.Parse(urlErr.URL)
+			if parseErr != nil {
+				u = req.URL
+			}
+			urlErr.URL = redactURL(u)
+		}
+		return nil, err
+	}
+	if err := FromResponse(resp); err != nil {
+		return nil, errm.With(err, "duration", time.Since(start))
+	}
+	return resp, nil
+}
+
+// Transport is an [http.RoundTripper] that returns errors of the errm package for failed requests.
+// Errors have "method", "url" and "duration" fields, the URL is redacted like in [FromResponse].
+// Errors of canceled requests and requests that exceeded their deadlines have [errm.Canceled]
+// and [errm.DeadlineExceeded] codes, other failed requests have the [errm.Unavailable] code.
+//
+// [http.Client] wraps errors of the transport into [url.Error] with the raw URL in its message,
+// so the query is not redacted in err.Error() of http.Client methods, use [Do] to redact it too.
+//
+// Like any [http.RoundTripper], it returns responses with non-2xx statuses without errors,
+// use [Do] or [FromResponse] to turn them into errors:
+//
+//	client := &http.Client{Transport: &errmhttp.Transport{}}
+//	resp, err := errmhttp.Do(client, req)
+//	if errm.IsCode(err, errm.NotFound) {
+//		// ...
+//	}
+type Transport struct {
+	// Base is the transport that makes requests, [http.DefaultTransport] is used if it is nil.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		return resp, nil
+	}
+	code := errm.Unavailable
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = errm.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = errm.DeadlineExceeded
+	}
+	return nil, errm.Wrap(err, "request failed", code, "method", req.Method, "url", redactURL(req.URL), "duration", time.Since(start))
+}
+
+// redactURL returns the URL without the user info and the fragment and with masked values of the query.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	out := *u
+	out.User = nil
+	out.Fragment, out.RawFragment = "", ""
+	if out.RawQuery != "" {
+		query := out.Query()
+		for key := range query {
+			query[key] = []string{"xxxxx"}
+		}
+		out.RawQuery = query.Encode()
+	}
+	return out.String()
+}
+
+// problemError returns an error built from the problem+json body of the response or nil if there is no such body.
+func problemError(resp *http.Response) error {
+	if resp.Body == nil {
+		return nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxProblemSize))
+	_ = resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != ContentType {
+		return nil
+	}
+	var members map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&members); err != nil {
+		return nil
+	}
+
+	msg, _ := members["detail"].(string)
+	if msg == "" {
+		msg, _ = members["title"].(string)
+	}
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	code := CodeFromStatus(resp.StatusCode)
+	if c, ok := members["code"].(string); ok && c != "" {
+		code = errm.Code(c)
+	}
+
+	keys := make([]string, 0, len(members))
+	for key := range members {
+		switch key {
+		case "type", "title", "status", "detail", "instance", "code":
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	fields := make([]any, 0, len(keys)+1)
+	for _, key := range keys {
+		fields = append(fields, problemField(key, members[key]))
+	}
+	if code != "" {
+		fields = append(fields, code)
+	}
+	return errm.New(msg, fields...)
+}
+
+// problemField returns an extension member as a field, strings, bools and numbers are typed fields.
+func problemField(key string, value any) errm.Field {
+	switch v := value.(type) {
+	case string:
+		return errm.String(key, v)
+	case bool:
+		return errm.Bool(key, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return errm.Int64(key, i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return errm.Float64(key, f)
+		}
+	}
+	return errm.Any(key, value)
+}
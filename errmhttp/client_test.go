@@ -0,0 +1,153 @@
+package errmhttp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+	"github.com/maxbolgarin/errm/errmhttp"
+)
+
+func TestFromResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = io.WriteString(w, "ok")
+		case "/problem":
+			w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+			w.WriteHeader(http.StatusConflict)
+			_, _ = io.WriteString(w, `{"type":"about:blank","title":"Conflict","status":409,"detail":"user exists","code":"user_exists","user_id":42,"login":"bob"}`)
+		case "/title":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"title":"Not Found","status":404}`)
+		case "/invalid":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `not json`)
+		default:
+			http.Error(w, "service is down", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	url := strings.Replace(srv.URL, "http://", "http://user:secret@", 1)
+
+	testCases := []struct {
+		path string
+		code errm.Code
+		exp  string
+	}{
+		{path: "/ok", exp: ""},
+		{path: "/problem", code: "user_exists", exp: "request failed method=GET url=http://HOST/problem status=409: user exists login=bob user_id=42"},
+		{path: "/title", code: errm.NotFound, exp: "request failed method=GET url=http://HOST/title status=404: Not Found"},
+		{path: "/invalid", code: errm.NotFound, exp: "request failed method=GET url=http://HOST/invalid status=404"},
+		{path: "/down?token=abc&api_key=def#top", code: errm.Unavailable, exp: "request failed method=GET url=http://HOST/down?api_key=xxxxx&token=xxxxx status=503"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := http.Get(url + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			err = errmhttp.FromResponse(resp)
+			got := ""
+			if err != nil {
+				got = strings.ReplaceAll(err.Error(), strings.TrimPrefix(srv.URL, "http://"), "HOST")
+			}
+			if got != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, got)
+			}
+			if code := errm.CodeOf(err); code != tc.code {
+				t.Errorf("expected %q, got %q", tc.code, code)
+			}
+		})
+	}
+
+	if errmhttp.FromResponse(nil) != nil {
+		t.Errorf("expected nil")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(errmhttp.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		switch r.URL.Path {
+		case "/users/1":
+			_, err := io.WriteString(w, "bob")
+			return err
+		case "/slow":
+			<-r.Context().Done()
+			return nil
+		}
+		return errm.New("user not found", errm.NotFound, "user_id", 2, "table", "users")
+	}, errmhttp.ExposeFields("user_id")))
+	defer srv.Close()
+	client := &http.Client{Transport: &errmhttp.Transport{}}
+
+	resp, err := client.Get(srv.URL + "/users/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "bob" {
+		t.Errorf("expected bob, got %s", body)
+	}
+
+	resp, err = client.Get(srv.URL + "/users/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected the response with 404, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/users/2", nil)
+	if resp, err = errmhttp.Do(client, req); resp != nil || !errm.IsCode(err, errm.NotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	fields := errm.Fields(err)
+	if len(fields) != 10 || fields[0] != "method" || fields[4] != "status" || fields[6] != "duration" || fields[8] != "user_id" || fields[9] != int64(2) {
+		t.Errorf("expected method, url, status, duration and user_id fields, got %v", fields)
+	}
+	if errm.Contains(err, "table") {
+		t.Errorf("expected no unexposed fields, got %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/slow", nil)
+	if _, err := client.Do(req); !errm.IsCode(err, errm.DeadlineExceeded) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	srv.Close()
+	url := strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + "/users/1?token=abc"
+	_, err = client.Get(url)
+	if !errm.IsCode(err, errm.Unavailable) {
+		t.Errorf("expected unavailable, got %v", err)
+	}
+	if fields := errm.Fields(err); len(fields) < 4 || strings.Contains(fields[3].(string), "user:") || strings.Contains(fields[3].(string), "abc") {
+		t.Errorf("expected a redacted url, got %v", fields)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, url, nil)
+	if _, err = errmhttp.Do(client, req); !errm.IsCode(err, errm.Unavailable) {
+		t.Errorf("expected unavailable, got %v", err)
+	}
+	if msg := err.Error(); strings.Contains(msg, "user:") || strings.Contains(msg, "abc") {
+		t.Errorf("expected a redacted url, got %s", msg)
+	}
+	if _, err = errmhttp.Do(http.DefaultClient, req); err == nil || strings.Contains(err.Error(), "abc") {
+		t.Errorf("expected a redacted url, got %v", err)
+	}
+}
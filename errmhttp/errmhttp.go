@@ -0,0 +1,208 @@
+// Package errmhttp writes errors of the errm package as RFC 9457 Problem Details responses:
+//
+//	http.Handle("/users/", errmhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		id := r.PathValue("id")
+//		user, err := load(r.Context(), id)
+//		if err != nil {
+//			return errm.Wrap(err, "cannot load user", "user_id", id)
+//		}
+//		return json.NewEncoder(w).Encode(user)
+//	}))
+//
+// Responses contain only data that is safe to expose: the status with its title, the code of the error,
+// the public message of the error as the detail, see [errm.PublicMessage], and fields that are exposed explicitly using [ExposeFields]. Messages of errors and stack traces are not written.
+//
+// On the client side, [FromResponse], [Do] and [Transport] turn error responses and failed requests
+// into errors of the errm package.
+package errmhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/maxbolgarin/errm"
+)
+
+// ContentType is the media type of Problem Details responses.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 9457 Problem Details object. Extension members are encoded next to the standard members.
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Code is the code of the error, it is encoded as the "code" extension member.
+	Code errm.Code
+	// Extensions are other extension members, they don't override the standard members and the code.
+	Extensions map[string]any
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (p Problem) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(p.Extensions)+6)
+	for k, v := range p.Extensions {
+		switch k {
+		case "type", "title", "status", "detail", "instance", "code":
+			continue
+		}
+		out[k] = v
+	}
+	out["type"] = p.Type
+	out["status"] = p.Status
+	for k, v := range map[string]string{"title": p.Title, "detail": p.Detail, "instance": p.Instance, "code": string(p.Code)} {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Option configures how errors are written to responses.
+type Option func(*config)
+
+type config struct {
+	fields []string
+}
+
+// ExposeFields makes [WriteError] add fields of the error with the given keys as extension members of responses.
+// Only the outermost field with a key is used. Fields are not exposed by default, because they may contain
+// internal data, e.g. queries and hosts.
+func ExposeFields(keys ...string) Option {
+	return func(c *config) {
+		c.fields = append(c.fields, keys...)
+	}
+}
+
+// NewProblem returns the Problem Details of the error for the request, see [WriteError].
+func NewProblem(r *http.Request, err error, opts ...Option) Problem {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	code := errm.CodeOf(err)
+	status := StatusFromCode(code)
+	p := Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: errm.PublicMessage(err),
+		Code:   code,
+	}
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.Path
+	}
+
+	if len(c.fields) == 0 {
+		return p
+	}
+	fields := errm.Fields(err)
+	for _, key := range c.fields {
+		for i := 0; i+1 < len(fields); i += 2 {
+			if fields[i] != key {
+				continue
+			}
+			if p.Extensions == nil {
+				p.Extensions = make(map[string]any, len(c.fields))
+			}
+			p.Extensions[key] = fields[i+1]
+			break
+		}
+	}
+	return p
+}
+
+// WriteError writes the error as an application/problem+json response with the status that corresponds to
+// the code of the error, see [StatusFromCode]. It is noop for a nil error.
+func WriteError(w http.ResponseWriter, r *http.Request, err error, opts ...Option) {
+	if err == nil {
+		return
+	}
+	p := NewProblem(r, err, opts...)
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// HandlerFunc is an HTTP handler that returns an error, the error is written using [WriteError].
+// Use [Handler] to set options.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP implements [http.Handler].
+func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, fn(w, r))
+}
+
+// Handler returns an [http.Handler] that writes errors of the function using [WriteError] with the options.
+func Handler(fn HandlerFunc, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, fn(w, r), opts...)
+	})
+}
+
+// StatusFromCode returns the HTTP status code that corresponds to the code, e.g. 404 for [errm.NotFound].
+// It returns 500 for [errm.Internal], an empty code and unknown codes.
+func StatusFromCode(code errm.Code) int {
+	switch code {
+	case errm.InvalidArgument, errm.FailedPrecondition:
+		return http.StatusBadRequest
+	case errm.NotFound:
+		return http.StatusNotFound
+	case errm.AlreadyExists:
+		return http.StatusConflict
+	case errm.PermissionDenied:
+		return http.StatusForbidden
+	case errm.Unauthenticated:
+		return http.StatusUnauthorized
+	case errm.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case errm.Canceled:
+		return statusClientClosedRequest
+	case errm.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case errm.Unavailable:
+		return http.StatusServiceUnavailable
+	case errm.Unimplemented:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
+// statusClientClosedRequest is a non-standard status of nginx for requests canceled by clients.
+const statusClientClosedRequest = 499
+
+// CodeFromStatus returns the code that corresponds to the HTTP status code, e.g. [errm.NotFound] for 404.
+// It returns [errm.Internal] for unknown 5xx statuses and an empty code for other statuses.
+func CodeFromStatus(status int) errm.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return errm.InvalidArgument
+	case http.StatusUnauthorized:
+		return errm.Unauthenticated
+	case http.StatusForbidden:
+		return errm.PermissionDenied
+	case http.StatusNotFound:
+		return errm.NotFound
+	case http.StatusConflict:
+		return errm.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return errm.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return errm.ResourceExhausted
+	case statusClientClosedRequest:
+		return errm.Canceled
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return errm.DeadlineExceeded
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return errm.Unavailable
+	case http.StatusNotImplemented:
+		return errm.Unimplemented
+	}
+	if status >= 500 && status < 600 {
+		return errm.Internal
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package errmhttp_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+	"github.com/maxbolgarin/errm/errmhttp"
+)
+
+func TestWriteError(t *testing.T) {
+	testCases := []struct {
+		id     string
+		err    error
+		opts   []errmhttp.Option
+		status int
+		exp    string
+	}{
+		{
+			id:     "not_found",
+			err:    errm.Wrap(errm.New("no rows", errm.NotFound, "query", "SELECT 1"), "cannot load", "user_id", 5),
+			status: http.StatusNotFound,
+			exp:    `{"code":"not_found","detail":"Not found","instance":"/users/5","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			id:     "exposed_fields",
+			err:    errm.Wrap(errm.New("no rows", errm.NotFound, "user_id", 6), "cannot load", "user_id", 5),
+			opts:   []errmhttp.Option{errmhttp.ExposeFields("user_id", "missing", "status")},
+			status: http.StatusNotFound,
+			exp:    `{"code":"not_found","detail":"Not found","instance":"/users/5","status":404,"title":"Not Found","type":"about:blank","user_id":5}`,
+		},
+		{
+			id:     "public",
+			err:    errm.Wrap(errm.WithPublic(errm.New("no rows", errm.NotFound), "Order not found"), "cannot load", "host", "db"),
+			status: http.StatusNotFound,
+			exp:    `{"code":"not_found","detail":"Order not found","instance":"/users/5","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+		{
+			id:     "no_code",
+			err:    errm.New("connection refused", "host", "10.0.0.1"),
+			status: http.StatusInternalServerError,
+			exp:    `{"detail":"Internal error","instance":"/users/5","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+		{
+			id:     "external",
+			err:    io.EOF,
+			status: http.StatusInternalServerError,
+			exp:    `{"detail":"Internal error","instance":"/users/5","status":500,"title":"Internal Server Error","type":"about:blank"}`,
+		},
+		{
+			id:     "canceled",
+			err:    errm.New("canceled", errm.Canceled),
+			status: 499,
+			exp:    `{"code":"canceled","detail":"Canceled","instance":"/users/5","status":499,"type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			errmhttp.WriteError(w, httptest.NewRequest(http.MethodGet, "/users/5?debug=1", nil), tc.err, tc.opts...)
+
+			if w.Code != tc.status {
+				t.Errorf("expected %d, got %d", tc.status, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != errmhttp.ContentType {
+				t.Errorf("expected %s, got %s", errmhttp.ContentType, ct)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, got)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	errmhttp.WriteError(w, nil, nil)
+	if w.Body.Len() != 0 || w.Code != http.StatusOK {
+		t.Errorf("expected nothing written, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestHandlerFunc(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/ok", errmhttp.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, "ok")
+		return err
+	}))
+	mux.Handle("/users/{id}", errmhttp.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return errm.New("user not found", errm.NotFound, "user_id", r.PathValue("id"))
+	}, errmhttp.ExposeFields("user_id")))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("expected 200 ok, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(srv.URL + "/users/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var p map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound || p["code"] != "not_found" || p["user_id"] != "42" {
+		t.Errorf("expected not found problem, got %d %v", resp.StatusCode, p)
+	}
+	if p["detail"] != "Not found" {
+		t.Errorf("expected a public message, got %v", p["detail"])
+	}
+}
+
+func TestStatusFromCode(t *testing.T) {
+	for code, exp := range map[errm.Code]int{
+		"":                       500,
+		errm.Internal:            500,
+		errm.InvalidArgument:     400,
+		errm.NotFound:            404,
+		errm.AlreadyExists:       409,
+		errm.PermissionDenied:    403,
+		errm.Unauthenticated:     401,
+		errm.ResourceExhausted:   429,
+		errm.DeadlineExceeded:    504,
+		errm.Unavailable:         503,
+		errm.Unimplemented:       501,
+		errm.Code("custom_code"): 500,
+	} {
+		if got := errmhttp.StatusFromCode(code); got != exp {
+			t.Errorf("expected %d for %q, got %d", exp, code, got)
+		}
+	}
+	for status, exp := range map[int]errm.Code{200: "", 302: "", 404: errm.NotFound, 408: errm.DeadlineExceeded, 418: "", 499: errm.Canceled, 502: errm.Unavailable, 599: errm.Internal} {
+		if got := errmhttp.CodeFromStatus(status); got != exp {
+			t.Errorf("expected %q for %d, got %q", exp, status, got)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package errm
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ExpiringSet object is useful for suppressing floods of the same error in logs.
+// It remembers a key of every error for a time window (TTL) and keeps at most a limited number of keys,
+// the least recently seen keys are evicted first. It is safe for concurrent/parallel usage.
+//
+//	s := errm.NewExpiringSet(time.Minute, 1000)
+//	if ok, suppressed := s.ShouldReport(err); ok {
+//		log.Error("cannot connect", "error", err, "suppressed", suppressed)
+//	}
+type ExpiringSet struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	limit int
+	key   func(error) string
+	items map[string]*list.Element
+	lru   *list.List // front is the most recently seen
+	now   func() time.Time
+}
+
+type expiringEntry struct {
+	key         string
+	err         error
+	windowStart time.Time
+	lastSeen    time.Time
+	suppressed  int
+}
+
+// NewExpiringSet returns a new [ExpiringSet] instance with the provided time window for every key and
+// the maximum number of keys. It uses err.Error() as a key, there is no limit if maxEntries is not positive.
+func NewExpiringSet(ttl time.Duration, maxEntries int) *ExpiringSet {
+	return NewExpiringSetWithKey(ttl, maxEntries, KeyByMessage)
+}
+
+// NewExpiringSetWithKey returns a new [ExpiringSet] instance that uses the provided function to make a key,
+// e.g. [KeyByTemplate] to suppress errors that differ only by field values. See [NewExpiringSet] for details.
+func NewExpiringSetWithKey(ttl time.Duration, maxEntries int, key func(error) string) *ExpiringSet {
+	return &ExpiringSet{
+		ttl:   ttl,
+		limit: maxEntries,
+		key:   key,
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+		now:   time.Now,
+	}
+}
+
+// ShouldReport returns true if it is the first time the key of the error shows up within the time window.
+// In that case it also returns how many times the key was suppressed during the previous window.
+// Otherwise it returns false and how many times the key was suppressed during the current window, including this one.
+// It returns false for a nil error. It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) ShouldReport(err error) (bool, int) {
+	if err == nil {
+		return false, 0
+	}
+	key := e.key(err)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if el, ok := e.items[key]; ok {
+		entry := el.Value.(*expiringEntry)
+		entry.err = err
+		entry.lastSeen = now
+		e.lru.MoveToFront(el)
+		if now.Sub(entry.windowStart) < e.ttl {
+			entry.suppressed++
+			return false, entry.suppressed
+		}
+		suppressed := entry.suppressed
+		entry.windowStart = now
+		entry.suppressed = 0
+		return true, suppressed
+	}
+
+	e.evict(now)
+	e.items[key] = e.lru.PushFront(&expiringEntry{
+		key:         key,
+		err:         err,
+		windowStart: now,
+		lastSeen:    now,
+	})
+	return true, 0
+}
+
+// Add records an error like [ExpiringSet.ShouldReport] does. It is noop if you provide a nil error.
+// It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Add(err error) {
+	e.ShouldReport(err)
+}
+
+// Has returns true if the [ExpiringSet] contains the given error. It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Has(err error, errs ...error) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for el := e.lru.Front(); el != nil; el = el.Next() {
+		if Is(el.Value.(*expiringEntry).err, err, errs...) {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns entries from [ExpiringSet] starting from the least recently seen one.
+// [SetEntry.Count] is the number of occurrences in the current window, [SetEntry.FirstSeen] is the start of the window.
+// It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Entries() []SetEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.snapshot().entries
+}
+
+// MaxSeverity returns the maximum [Severity] of errors in the [ExpiringSet] or 0 if it is empty.
+// It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) MaxSeverity() Level {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out Level
+	for el := e.lru.Front(); el != nil; el = el.Next() {
+		out = max(out, Severity(el.Value.(*expiringEntry).err))
+	}
+	return out
+}
+
+// Err returns a snapshot of [ExpiringSet] as error interface or nil if it is empty.
+// It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.snapshot().Err()
+}
+
+// snapshot returns current keys as a [Set] starting from the least recently seen one.
+func (e *ExpiringSet) snapshot() *Set {
+	s := NewSetWithCapacity(e.lru.Len())
+	s.key = e.key
+	for el := e.lru.Back(); el != nil; el = el.Prev() {
+		entry := el.Value.(*expiringEntry)
+		s.index[entry.key] = len(s.entries)
+		s.entries = append(s.entries, SetEntry{
+			Err:       entry.err,
+			Count:     entry.suppressed + 1,
+			FirstSeen: entry.windowStart,
+			LastSeen:  entry.lastSeen,
+		})
+	}
+	return s
+}
+
+// Empty return true if the [ExpiringSet] collector is empty. It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Empty() bool {
+	return e.Len() == 0
+}
+
+// Clear removes all keys. It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Clear() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.items = make(map[string]*list.Element)
+	e.lru.Init()
+}
+
+// Len returns the number of keys in [ExpiringSet]. It is safe for concurrent/parallel usage.
+func (e *ExpiringSet) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lru.Len()
+}
+
+// evict removes keys that were not seen during the whole time window and
+// the least recently seen keys to make a room for a new one.
+func (e *ExpiringSet) evict(now time.Time) {
+	for el := e.lru.Back(); el != nil; el = e.lru.Back() {
+		entry := el.Value.(*expiringEntry)
+		if now.Sub(entry.lastSeen) < e.ttl && (e.limit <= 0 || e.lru.Len() < e.limit) {
+			return
+		}
+		e.lru.Remove(el)
+		delete(e.items, entry.key)
+	}
+}
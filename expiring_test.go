@@ -0,0 +1,124 @@
+package errm_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestExpiringSetShouldReport(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	s := errm.NewExpiringSet(time.Minute, 10)
+	s.SetClock(clock.Now)
+
+	err := errm.New("connection refused", "host", "db")
+
+	ok, suppressed := s.ShouldReport(err)
+	if !ok || suppressed != 0 {
+		t.Errorf("expected true and 0, got %t and %d", ok, suppressed)
+	}
+	for i := 1; i <= 100; i++ {
+		clock.Add(time.Millisecond)
+		ok, suppressed = s.ShouldReport(err)
+		if ok || suppressed != i {
+			t.Fatalf("expected false and %d, got %t and %d", i, ok, suppressed)
+		}
+	}
+
+	exp := "connection refused host=db (x101)"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+	if !errm.Is(s.Err(), err) || !s.Has(err) {
+		t.Errorf("expected true, got false")
+	}
+
+	clock.Add(time.Minute)
+	ok, suppressed = s.ShouldReport(err)
+	if !ok || suppressed != 100 {
+		t.Errorf("expected true and 100, got %t and %d", ok, suppressed)
+	}
+	ok, suppressed = s.ShouldReport(err)
+	if ok || suppressed != 1 {
+		t.Errorf("expected false and 1, got %t and %d", ok, suppressed)
+	}
+
+	if ok, _ := s.ShouldReport(nil); ok {
+		t.Errorf("expected false for nil error")
+	}
+}
+
+func TestExpiringSetEviction(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	s := errm.NewExpiringSet(time.Minute, 2)
+	s.SetClock(clock.Now)
+
+	a, b, c := errors.New("A"), errors.New("B"), errors.New("C")
+	s.Add(a)
+	s.Add(b)
+	s.Add(a)
+	s.Add(c) // evicts B as the least recently seen
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+	if s.Has(b) {
+		t.Errorf("expected B to be evicted")
+	}
+	if ok, _ := s.ShouldReport(b); !ok { // evicts A
+		t.Errorf("expected true for evicted key")
+	}
+
+	exp := "C; B"
+	if got := s.Err().Error(); got != exp {
+		t.Errorf("expected %s, got %s", exp, got)
+	}
+
+	clock.Add(2 * time.Minute)
+	s.Add(errors.New("D")) // evicts all idle keys
+	if s.Len() != 1 {
+		t.Errorf("expected 1, got %d", s.Len())
+	}
+
+	s.Clear()
+	if !s.Empty() || s.Err() != nil {
+		t.Errorf("expected empty, got %d", s.Len())
+	}
+}
+
+func TestExpiringSetWithKey(t *testing.T) {
+	s := errm.NewExpiringSetWithKey(time.Hour, 0, errm.KeyByTemplate)
+
+	var wg sync.WaitGroup
+	reported := make(chan struct{}, 1000)
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := s.ShouldReport(errm.New("not found", "id", i)); ok {
+				reported <- struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(reported) != 1 {
+		t.Errorf("expected 1 report, got %d", len(reported))
+	}
+	if entries := s.Entries(); len(entries) != 1 || entries[0].Count != 1000 {
+		t.Errorf("expected a single entry with 1000 occurrences, got %v", entries)
+	}
+}
@@ -0,0 +1,32 @@
+package errm
+
+import (
+	"hash/maphash"
+	"time"
+
+	"github.com/maxbolgarin/errm/internal/fmtscan"
+)
+
+// NewCompactSetWithHash returns a [CompactSet] with a custom hash function to test collisions.
+func NewCompactSetWithHash(hash func(error) uint64) *CompactSet {
+	s := NewCompactSet()
+	s.hash = hash
+	return s
+}
+
+// CompactHashIsMessageHash reports whether [CompactSet] hashes err the same way as its message.
+func CompactHashIsMessageHash(err error) bool {
+	s := NewCompactSet()
+	return s.hashError(err) == maphash.String(s.seed, err.Error())
+}
+
+// SetClock replaces the clock of [ExpiringSet].
+func (e *ExpiringSet) SetClock(now func() time.Time) {
+	e.now = now
+}
+
+// FormatArgsNumber returns the number of operands used by a printf format.
+func FormatArgsNumber(format string) int {
+	_, n := fmtscan.Scan(format)
+	return n
+}
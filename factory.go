@@ -0,0 +1,203 @@
+package errm
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// Factory creates errors with preset fields, a namespace and options, like a logger created using With does it.
+// It has the same New, Errorf, Wrap and Wrapf methods as the package functions, it is safe for concurrent usage.
+//
+//	errs := errm.NewFactory(errm.Namespace("orders"), errm.BaseFields("service", "orders"))
+//	errs.New("not found", "order_id", 42)
+//	// orders: not found service=orders order_id=42
+type Factory struct {
+	namespace string
+	fields    []any
+	opts      options
+}
+
+// FactoryOption is an option of [NewFactory].
+type FactoryOption func(*Factory)
+
+// StackMode defines whether errors capture stack traces.
+type StackMode uint8
+
+const (
+	// StackFull captures the stack trace of the place where an error is created, it is the default mode.
+	StackFull StackMode = iota
+	// StackNone doesn't capture stack traces, it makes creating errors cheaper.
+	StackNone
+)
+
+// Formatter renders a message of a single error layer with its fields instead of the default msg field=val format.
+type Formatter func(msg string, fields []Field) string
+
+// Namespace adds a prefix to messages of errors in a "namespace: msg" format.
+func Namespace(name string) FactoryOption {
+	return func(f *Factory) {
+		f.namespace = name
+	}
+}
+
+// BaseFields adds pairs of fields to every error before fields of the call. A [Code] sets the default code.
+func BaseFields(fields ...any) FactoryOption {
+	return func(f *Factory) {
+		f.fields = append(f.fields, normalizeFields(fields)...)
+	}
+}
+
+// StackTrace sets the [StackMode] of errors, [StackFull] is the default one.
+func StackTrace(mode StackMode) FactoryOption {
+	return func(f *Factory) {
+		f.opts.noStack = mode == StackNone
+	}
+}
+
+// MessageFormatter sets a [Formatter] of messages, it is applied to layers created by the factory.
+func MessageFormatter(fn Formatter) FactoryOption {
+	return func(f *Factory) {
+		f.opts.formatter = fn
+	}
+}
+
+// NewFactory returns a new [Factory] instance with the provided options.
+func NewFactory(opts ...FactoryOption) *Factory {
+	f := &Factory{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// With returns a copy of the [Factory] that adds the provided fields to every error after the fields of f.
+func (f *Factory) With(fields ...any) *Factory {
+	out := *f
+	out.fields = append(slices.Clip(f.fields), normalizeFields(fields)...)
+	return &out
+}
+
+// New creates a new error like [New] does it.
+func (f *Factory) New(msg string, fields ...any) error {
+	l := f.opts.layer(nil, f.message(msg), f.merge(fields))
+	if f.opts.noStack {
+		return newError(&plainError{msg: l.render()}, l)
+	}
+	return newError(eris.New(l.render()), l)
+}
+
+// Errorf creates a new error with a formatted message like [Errorf] does it.
+func (f *Factory) Errorf(format string, args ...any) error {
+	args, fields := separateArgsAndFields(format, args)
+	l := f.opts.formatLayer(nil, f.format(format), args, f.merge(fields))
+	if f.opts.noStack {
+		return newError(&plainError{msg: l.render()}, l)
+	}
+	return newError(eris.New(l.render()), l)
+}
+
+// Wrap adds additional context to the error like [Wrap] does it.
+func (f *Factory) Wrap(err error, msg string, fields ...any) error {
+	l := f.opts.layer(err, f.message(msg), f.merge(fields))
+	switch {
+	case f.opts.noStack:
+		return newError(&plainError{msg: l.render(), err: unwrap(err)}, l)
+	case err == nil:
+		return newError(eris.New(l.render()), l)
+	}
+	return newError(eris.Wrap(unwrap(err), l.render()), l)
+}
+
+// Wrapf adds additional context to the error with a formatted message like [Wrapf] does it.
+func (f *Factory) Wrapf(err error, format string, args ...any) error {
+	args, fields := separateArgsAndFields(format, args)
+	l := f.opts.formatLayer(err, f.format(format), args, f.merge(fields))
+	switch {
+	case f.opts.noStack:
+		return newError(&plainError{msg: l.render(), err: unwrap(err)}, l)
+	case err == nil:
+		return newError(eris.New(l.render()), l)
+	}
+	return newError(eris.Wrap(unwrap(err), l.render()), l)
+}
+
+func (f *Factory) message(msg string) string {
+	switch {
+	case f.namespace == "":
+		return msg
+	case msg == "":
+		return f.namespace
+	}
+	return f.namespace + ": " + msg
+}
+
+// format returns the format with the namespace, which is escaped because it is not a format.
+func (f *Factory) format(format string) string {
+	if f.namespace == "" {
+		return format
+	}
+	ns := strings.ReplaceAll(f.namespace, "%", "%%")
+	if format == "" {
+		return ns
+	}
+	return ns + ": " + format
+}
+
+func (f *Factory) merge(fields []any) []any {
+	if len(f.fields) == 0 {
+		return fields
+	}
+	out := make([]any, 0, len(f.fields)+len(fields))
+	out = append(out, f.fields...)
+	return append(out, fields...)
+}
+
+// normalizeFields returns codes and fields as [Field] values, so a key without a value can't take
+// a value from the next fields.
+func normalizeFields(fields []any) []any {
+	out := make([]any, 0, len(fields))
+	for _, f := range fields {
+		if code, ok := f.(Code); ok {
+			out = append(out, code)
+		}
+	}
+	forEachField(fields, func(f Field) {
+		out = append(out, f)
+	})
+	return out
+}
+
+// fieldList returns fields as [Field] values.
+func fieldList(fields []any) []Field {
+	out := make([]Field, 0, len(fields))
+	forEachField(fields, func(f Field) {
+		out = append(out, f)
+	})
+	return out
+}
+
+// plainError is an error chain without a stack trace, it is used by factories with [StackNone].
+// It matches only itself and the error of this package that it belongs to, so unrelated errors
+// with the same message don't match each other.
+type plainError struct {
+	msg string
+	err error
+}
+
+func (e *plainError) Error() string {
+	if e.err == nil {
+		return e.msg
+	}
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *plainError) Unwrap() error {
+	return e.err
+}
+
+func (e *plainError) Is(target error) bool {
+	t, ok := target.(errorImpl)
+	return ok && t.err == error(e)
+}
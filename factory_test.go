@@ -0,0 +1,150 @@
+package errm_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestFactory(t *testing.T) {
+	f := errm.NewFactory(errm.Namespace("orders"), errm.BaseFields("service", "orders", errm.Internal))
+	db := f.With("component", "db")
+
+	testCases := []struct {
+		id  string
+		err error
+		exp string
+	}{
+		{id: "new", err: f.New("not found", "order_id", 42), exp: "orders: not found service=orders order_id=42"},
+		{id: "new_empty", err: f.New(""), exp: "orders service=orders"},
+		{id: "with", err: db.New("timeout"), exp: "orders: timeout service=orders component=db"},
+		{id: "errorf", err: f.Errorf("order %d not found", 42, "user", "bob"), exp: "orders: order 42 not found service=orders user=bob"},
+		{id: "errorf_missing", err: f.Errorf("order %d in %s", 42), exp: "orders: order 42 in %!s(MISSING) service=orders"},
+		{id: "wrap", err: f.Wrap(io.EOF, "cannot read"), exp: "orders: cannot read service=orders: EOF"},
+		{id: "wrap_nil", err: f.Wrap(nil, "cannot read"), exp: "orders: cannot read service=orders"},
+		{id: "wrapf", err: db.Wrapf(io.EOF, "cannot read %s", "file", "size", 1), exp: "orders: cannot read file service=orders component=db size=1: EOF"},
+		{id: "percent", err: errm.NewFactory(errm.Namespace("100%")).Errorf("user %d", 1), exp: "100%: user 1"},
+		{id: "dangling_base_key", err: errm.NewFactory(errm.BaseFields("service")).New("x", "id", 1), exp: "x service=!MISSING id=1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if tc.err.Error() != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, tc.err)
+			}
+			if !errm.CompactHashIsMessageHash(tc.err) {
+				t.Errorf("expected the same hash as for the message")
+			}
+		})
+	}
+
+	if exp := "orders: timeout service=orders"; f.New("timeout").Error() != exp {
+		t.Errorf("expected %s, got %s", exp, f.New("timeout"))
+	}
+	if code := errm.CodeOf(f.New("x")); code != errm.Internal {
+		t.Errorf("expected %s, got %s", errm.Internal, code)
+	}
+	if code := errm.CodeOf(f.New("x", errm.NotFound)); code != errm.NotFound {
+		t.Errorf("expected %s, got %s", errm.NotFound, code)
+	}
+	if err := f.Wrapf(errm.New("x"), "load: %w", io.EOF); !errors.Is(err, io.EOF) {
+		t.Errorf("expected true, got false")
+	}
+}
+
+func TestFactoryStackMode(t *testing.T) {
+	full := errm.NewFactory().New("x", "id", 1)
+	if !strings.HasPrefix(fmt.Sprintf("%+v", full), "x id=1\n\t") {
+		t.Errorf("expected a stack trace, got %+v", full)
+	}
+
+	f := errm.NewFactory(errm.StackTrace(errm.StackNone))
+	err := f.New("x", "id", 1)
+	if got := fmt.Sprintf("%+v", err); got != "x id=1" {
+		t.Errorf("expected no stack trace, got %s", got)
+	}
+	if !errm.Is(err, err) || !errors.Is(err, err) {
+		t.Errorf("expected true, got false")
+	}
+	if other := f.New("x", "id", 1); errm.Is(err, full) || errm.Is(err, other) || errors.Is(err, other) {
+		t.Errorf("expected unrelated errors with the same message not to match")
+	}
+
+	wrapped := f.Wrap(err, "y")
+	if exp := "y: x id=1"; wrapped.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, wrapped)
+	}
+	if !errm.Is(wrapped, err) || !errors.Is(wrapped, err) {
+		t.Errorf("expected true, got false")
+	}
+	if external := f.Wrapf(io.EOF, "read %s", "a.txt"); !errors.Is(external, io.EOF) || !errm.Is(external, io.EOF) {
+		t.Errorf("expected true, got false")
+	}
+	if outer := errm.Wrap(wrapped, "z"); !errm.Is(outer, err) || outer.Error() != "z: y: x id=1" {
+		t.Errorf("expected true for %s", outer)
+	}
+}
+
+func TestStackFrames(t *testing.T) {
+	f := errm.NewFactory(errm.Namespace("orders"))
+	testCases := []struct {
+		fn  string
+		err error
+	}{
+		{"errm.New", errm.New("x")},
+		{"errm.Errorf", errm.Errorf("x %d", 1)},
+		{"errm.Wrap", errm.Wrap(io.EOF, "x")},
+		{"errm.Wrap", errm.Wrap(nil, "x")},
+		{"errm.Wrapf", errm.Wrapf(io.EOF, "x %d", 1)},
+		{"errm.WrapMany", errm.WrapMany(nil, "x")},
+		{"errm.T", errm.T("x {id}", "id", 1)},
+		{"errm.NewFields", errm.NewFields("x", errm.Int("id", 1))},
+		{"errm.WrapFields", errm.WrapFields(io.EOF, "x")},
+		{"errm.(*Factory).New", f.New("x")},
+		{"errm.(*Factory).Errorf", f.Errorf("x %d", 1)},
+		{"errm.(*Factory).Wrap", f.Wrap(io.EOF, "x")},
+		{"errm.(*Factory).Wrapf", f.Wrapf(nil, "x %d", 1)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.fn, func(t *testing.T) {
+			lines := strings.Split(fmt.Sprintf("%+v", tc.err), "\n\t")
+			if len(lines) < 2 || !strings.HasPrefix(lines[1], "errm_test.TestStackFrames") {
+				t.Errorf("expected the caller of %s at the top of the stack, got %q", tc.fn, lines[1:])
+			}
+			for _, line := range lines[1:] {
+				if strings.HasPrefix(line, "errm.") || strings.HasPrefix(line, "runtime.") {
+					t.Errorf("expected no internal frames, got %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestFactoryFormatter(t *testing.T) {
+	f := errm.NewFactory(errm.MessageFormatter(func(msg string, fields []errm.Field) string {
+		parts := make([]string, len(fields))
+		for i, f := range fields {
+			parts[i] = f.Key + ": " + fmt.Sprint(f.Value())
+		}
+		return msg + " {" + strings.Join(parts, ", ") + "}"
+	}))
+
+	err := errm.Wrap(f.New("not found", "id", 1, "table", "users"), "cannot load", "user", "bob")
+	if exp := "cannot load user=bob: not found {id: 1, table: users}"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if !errm.CompactHashIsMessageHash(err) {
+		t.Errorf("expected the same hash as for the message")
+	}
+
+	s := errm.NewCompactSetWithHash(func(error) uint64 { return 1 })
+	s.Add(f.New("A", "id", 1))
+	s.Add(errm.New("A", "id", 1))
+	s.Add(f.New("A", "id", 1))
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+}
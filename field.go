@@ -0,0 +1,261 @@
+package errm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// Field is a typed key-value pair that can be used in field lists next to alternating key-value pairs:
+//
+//	errm.New("cannot connect", errm.String("host", host), errm.Int("port", port), errm.Dur("timeout", timeout))
+//
+// Typed fields keep value types and render values without fmt. A Field passed to [New] or [Wrap] is boxed into
+// an interface like any other value, use [NewFields] and [WrapFields] to create errors without boxing.
+type Field struct {
+	Key string
+
+	kind  fieldKind
+	num   uint64
+	str   string
+	value any // value of Any and Err, location of Time
+}
+
+type fieldKind uint8
+
+const (
+	kindAny fieldKind = iota
+	kindString
+	kindInt64
+	kindFloat64
+	kindBool
+	kindDuration
+	kindTime
+	kindError
+)
+
+// String returns a field with a string value.
+func String(key, value string) Field {
+	return Field{Key: key, kind: kindString, str: value}
+}
+
+// Int returns a field with an int value, it is stored as int64.
+func Int(key string, value int) Field {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns a field with an int64 value.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, kind: kindInt64, num: uint64(value)}
+}
+
+// Float64 returns a field with a float64 value.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, kind: kindFloat64, num: math.Float64bits(value)}
+}
+
+// Bool returns a field with a bool value.
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, kind: kindBool}
+	if value {
+		f.num = 1
+	}
+	return f
+}
+
+// Dur returns a field with a [time.Duration] value.
+func Dur(key string, value time.Duration) Field {
+	return Field{Key: key, kind: kindDuration, num: uint64(value)}
+}
+
+// Time returns a field with a [time.Time] value, the monotonic clock reading is dropped.
+func Time(key string, value time.Time) Field {
+	nsec := value.UnixNano()
+	if t := time.Unix(0, nsec); t.Equal(value) {
+		return Field{Key: key, kind: kindTime, num: uint64(nsec), value: value.Location()}
+	}
+	return Field{Key: key, kind: kindTime, value: value.Round(0)}
+}
+
+// Err returns a field with an error value, it is rendered as the error message.
+func Err(key string, err error) Field {
+	return Field{Key: key, kind: kindError, value: err}
+}
+
+// Any returns a field with a value of any type, it is rendered like fmt.Sprint(value) does it.
+func Any(key string, value any) Field {
+	return Field{Key: key, kind: kindAny, value: value}
+}
+
+// NewFields creates a new error with a static message and typed fields. Unlike [New], it keeps fields
+// without boxing them into interfaces, so it takes fewer allocations.
+func NewFields(msg string, fields ...Field) error {
+	l := &layer{msg: msg, typed: fields}
+	return newError(eris.New(l.render()), l)
+}
+
+// WrapFields adds additional context with typed fields to the error like [Wrap] does it.
+// Unlike [Wrap], it keeps fields without boxing them into interfaces, so it takes fewer allocations.
+func WrapFields(err error, msg string, fields ...Field) error {
+	if err == nil {
+		l := &layer{msg: msg, typed: fields}
+		return newError(eris.New(l.render()), l)
+	}
+	l := wrapLayer(err, msg, nil)
+	l.typed = fields
+	return newError(eris.Wrap(unwrap(err), l.render()), l)
+}
+
+// Value returns the value of the field, e.g. int64 for [Int] and [time.Time] for [Time].
+func (f Field) Value() any {
+	switch f.kind {
+	case kindString:
+		return f.str
+	case kindInt64:
+		return int64(f.num)
+	case kindFloat64:
+		return math.Float64frombits(f.num)
+	case kindBool:
+		return f.num == 1
+	case kindDuration:
+		return time.Duration(f.num)
+	case kindTime:
+		return f.time()
+	}
+	return f.value
+}
+
+// String returns the field in a key=value format.
+func (f Field) String() string {
+	var buf [64]byte
+	b, s := f.render(buf[:0])
+	return f.Key + "=" + string(b) + s
+}
+
+func (f Field) time() time.Time {
+	if loc, ok := f.value.(*time.Location); ok {
+		return time.Unix(0, int64(f.num)).In(loc)
+	}
+	t, _ := f.value.(time.Time)
+	return t
+}
+
+// render returns the value in the same format as fmt.Sprint(value) does. Numbers, bools, durations and times are appended
+// to buf without allocations, other values are returned as a string, so one of the results is always empty.
+func (f Field) render(buf []byte) ([]byte, string) {
+	switch f.kind {
+	case kindString:
+		return buf, f.str
+	case kindInt64:
+		return strconv.AppendInt(buf, int64(f.num), 10), ""
+	case kindFloat64:
+		return strconv.AppendFloat(buf, math.Float64frombits(f.num), 'g', -1, 64), ""
+	case kindBool:
+		return strconv.AppendBool(buf, f.num == 1), ""
+	case kindDuration:
+		return append(buf, time.Duration(f.num).String()...), "" // String is inlined, so it doesn't allocate here
+	case kindTime:
+		return f.time().AppendFormat(buf, "2006-01-02 15:04:05.999999999 -0700 MST"), ""
+	case kindError:
+		if f.value == nil {
+			return buf, "<nil>"
+		}
+		return buf, f.value.(error).Error()
+	}
+
+	switch v := f.value.(type) {
+	case string:
+		return buf, v
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10), ""
+	case int64:
+		return strconv.AppendInt(buf, v, 10), ""
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10), ""
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10), ""
+	case uint64:
+		return strconv.AppendUint(buf, v, 10), ""
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10), ""
+	case bool:
+		return strconv.AppendBool(buf, v), ""
+	}
+	return buf, fmt.Sprint(f.value)
+}
+
+// sameField reports whether fields have the same key and render the same value.
+func sameField(a, b Field) bool {
+	if a.Key != b.Key {
+		return false
+	}
+	if a.kind == b.kind && a.kind != kindAny && a.kind != kindError && a.kind != kindTime {
+		return a.num == b.num && a.str == b.str
+	}
+	var abuf, bbuf [64]byte
+	ab, as := a.render(abuf[:0])
+	bb, bs := b.render(bbuf[:0])
+	switch {
+	case len(ab) == 0 && len(bb) == 0:
+		return as == bs
+	case len(ab) == 0:
+		return as == string(bb)
+	case len(bb) == 0:
+		return string(ab) == bs
+	}
+	return string(ab) == string(bb)
+}
+
+// Markers of malformed field lists, they follow the log/slog package.
+const (
+	badKey       = "!BADKEY"
+	missingValue = "!MISSING"
+)
+
+var strict atomic.Bool
+
+// SetStrict enables or disables the strict mode. By default malformed field lists are rendered with markers:
+// a value without a string key is rendered as !BADKEY=value and a key without a value as key=!MISSING.
+// In the strict mode they panic, as well as formats of [Errorf] and [Wrapf] that need more arguments
+// than provided. It is useful to find such bugs in tests:
+//
+//	func TestMain(m *testing.M) {
+//		errm.SetStrict(true)
+//		os.Exit(m.Run())
+//	}
+func SetStrict(enabled bool) {
+	strict.Store(enabled)
+}
+
+// forEachField calls fn for every field: a [Field] or a key-value pair, it skips codes. Like log/slog, it takes
+// an element that is not a string, a [Field] or a [Code] as a value with the !BADKEY key and a key without value
+// as a key with the !MISSING value. It panics in these cases in the strict mode, see [SetStrict].
+func forEachField(fields []any, fn func(f Field)) {
+	for i := 0; i < len(fields); i++ {
+		switch key := fields[i].(type) {
+		case Field:
+			fn(key)
+		case Code:
+			// It is not a field, see [CodeOf].
+		case string:
+			if i+1 >= len(fields) {
+				if strict.Load() {
+					panic(fmt.Sprintf("errm: key %q has no value", key))
+				}
+				fn(Field{Key: key, kind: kindString, str: missingValue})
+				return
+			}
+			fn(Field{Key: key, value: fields[i+1]})
+			i++
+		default:
+			if strict.Load() {
+				panic(fmt.Sprintf("errm: key %v of type %T is not a string", key, key))
+			}
+			fn(Field{Key: badKey, value: key})
+		}
+	}
+}
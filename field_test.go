@@ -0,0 +1,215 @@
+package errm_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestFields(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC)
+	zone := time.FixedZone("MSK", 3*60*60)
+	var nilErr error
+
+	testCases := []struct {
+		id    string
+		field errm.Field
+		value any
+	}{
+		{id: "string", field: errm.String("k", "v w"), value: "v w"},
+		{id: "int", field: errm.Int("k", -42), value: int64(-42)},
+		{id: "int64", field: errm.Int64("k", math.MaxInt64), value: int64(math.MaxInt64)},
+		{id: "float", field: errm.Float64("k", 1.5), value: 1.5},
+		{id: "float_exp", field: errm.Float64("k", 1e21), value: 1e21},
+		{id: "float_nan", field: errm.Float64("k", math.NaN()), value: math.NaN()},
+		{id: "bool", field: errm.Bool("k", true), value: true},
+		{id: "dur", field: errm.Dur("k", 1500*time.Millisecond), value: 1500 * time.Millisecond},
+		{id: "time", field: errm.Time("k", at), value: at},
+		{id: "time_zone", field: errm.Time("k", at.In(zone)), value: at.In(zone)},
+		{id: "time_zero", field: errm.Time("k", time.Time{}), value: time.Time{}},
+		{id: "err", field: errm.Err("k", errors.New("boom")), value: errors.New("boom")},
+		{id: "err_nil", field: errm.Err("k", nil), value: nilErr},
+		{id: "any", field: errm.Any("k", []int{1, 2}), value: []int{1, 2}},
+		{id: "any_nil", field: errm.Any("k", nil), value: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			exp := errm.New("msg", "k", tc.value).Error()
+			if got := errm.New("msg", tc.field).Error(); got != exp {
+				t.Errorf("expected %s, got %s", exp, got)
+			}
+			if got := tc.field.String(); got != "k="+fmt.Sprint(tc.value) {
+				t.Errorf("expected k=%v, got %s", tc.value, got)
+			}
+			if got := tc.field.Value(); fmt.Sprint(got) != fmt.Sprint(tc.value) {
+				t.Errorf("expected %v, got %v", tc.value, got)
+			}
+			if !errm.CompactHashIsMessageHash(errm.New("msg", tc.field)) {
+				t.Errorf("expected the same hash as for the message")
+			}
+		})
+	}
+}
+
+func TestNewFields(t *testing.T) {
+	err := errm.NewFields("cannot connect", errm.String("host", "db"), errm.Int("port", 5432))
+	if exp := "cannot connect host=db port=5432"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if exp := []any{"host", "db", "port", int64(5432)}; !reflect.DeepEqual(errm.Fields(err), exp) {
+		t.Errorf("expected %v, got %v", exp, errm.Fields(err))
+	}
+
+	wrapped := errm.WrapFields(err, "cannot start", errm.Dur("timeout", time.Second))
+	if exp := "cannot start timeout=1s: cannot connect host=db port=5432"; wrapped.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, wrapped)
+	}
+	if !errm.Is(wrapped, err) {
+		t.Errorf("expected wrapped error")
+	}
+	if exp := errm.Wrap(errm.New("cannot connect", "host", "db", "port", 5432), "cannot start", "timeout", time.Second); !errm.CompactHashIsMessageHash(wrapped) || errm.Fingerprint(wrapped) != errm.Fingerprint(exp) {
+		t.Errorf("expected the same hash and fingerprint as for pairs")
+	}
+	if exp := "cannot start timeout=1s user_id=5: cannot connect host=db port=5432"; errm.With(wrapped, "user_id", 5).Error() != exp {
+		t.Errorf("expected %s, got %s", exp, errm.With(wrapped, "user_id", 5))
+	}
+	if got := errm.WrapFields(nil, "x", errm.Bool("ok", true)).Error(); got != "x ok=true" {
+		t.Errorf("expected x ok=true, got %s", got)
+	}
+}
+
+func TestFieldsMixed(t *testing.T) {
+	err := errm.Wrap(errm.New("not found", errm.Int("id", 1)), "cannot load", "table", "users", errm.Dur("took", time.Second))
+	if exp := "cannot load table=users took=1s: not found id=1"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	if exp := "cannot load table= took=: not found id="; errm.KeyByTemplate(err) != exp {
+		t.Errorf("expected %s, got %s", exp, errm.KeyByTemplate(err))
+	}
+	exp := []any{"table", "users", "took", time.Second, "id", int64(1)}
+	if got := errm.Fields(err); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	err = errm.Errorf("user %d", 42, errm.String("table", "users"))
+	if exp := "user 42 table=users"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+
+	err = errm.T("user {user_id} not found", errm.Int("user_id", 42), errm.String("table", "users"))
+	if exp := "user 42 not found table=users"; err.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, err)
+	}
+	exp = []any{"user_id", int64(42), "table", "users"}
+	if got := errm.Fields(err); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	s := errm.NewCompactSetWithHash(func(error) uint64 { return 1 })
+	s.New("A", errm.Int("id", 1))
+	s.New("A", "id", 1)
+	s.New("A", errm.String("id", "1"))
+	s.New("A", errm.Int("id", 2))
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+}
+
+func TestMalformedFields(t *testing.T) {
+	testCases := []struct {
+		id  string
+		err error
+		exp string
+	}{
+		{id: "missing", err: errm.New("x", "id"), exp: "x id=!MISSING"},
+		{id: "bad_key", err: errm.New("x", 42, "id"), exp: "x !BADKEY=42 id=!MISSING"},
+		{id: "bad_key_pair", err: errm.New("x", 1, "a", "b"), exp: "x !BADKEY=1 a=b"},
+		{id: "field_after_bad_key", err: errm.New("x", nil, errm.Int("id", 1)), exp: "x !BADKEY=<nil> id=1"},
+		{id: "template", err: errm.T("user {id}", "id", 1, 2), exp: "user 1 !BADKEY=2"},
+		{id: "errorf_fields", err: errm.Errorf("user %d", 1, "id"), exp: "user 1 id=!MISSING"},
+		{id: "errorf_missing_arg", err: errm.Errorf("user %d in %s", 1), exp: "user 1 in %!s(MISSING)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if tc.err.Error() != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, tc.err)
+			}
+			if !errm.CompactHashIsMessageHash(tc.err) {
+				t.Errorf("expected the same hash as for the message")
+			}
+		})
+	}
+}
+
+func TestSetStrict(t *testing.T) {
+	errm.SetStrict(true)
+	defer errm.SetStrict(false)
+
+	testCases := []struct {
+		id  string
+		new func() error
+		exp string
+	}{
+		{id: "missing", new: func() error { return errm.New("x", "id") }, exp: `errm: key "id" has no value`},
+		{id: "bad_key", new: func() error { return errm.Wrap(errm.New("y"), "x", 42, "id") }, exp: "errm: key 42 of type int is not a string"},
+		{id: "template", new: func() error { return errm.T("user {id}", "id") }, exp: `errm: key "id" has no value`},
+		{id: "errorf", new: func() error { return errm.Errorf("user %d in %s", 1) }, exp: `errm: format "user %d in %s" needs 2 arguments, got 1`},
+		{id: "wrapf", new: func() error { return errm.Wrapf(errm.New("y"), "user %d", "id", 1) }, exp: `errm: key 1 of type int is not a string`},
+		{id: "valid", new: func() error { return errm.Errorf("user %d", 1, "id", 2, errm.Int("n", 3)) }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			defer func() {
+				if got := fmt.Sprint(recover()); tc.exp != "" && got != tc.exp {
+					t.Errorf("expected panic %s, got %s", tc.exp, got)
+				} else if tc.exp == "" && got != "<nil>" {
+					t.Errorf("expected no panic, got %s", got)
+				}
+			}()
+			_ = tc.new()
+		})
+	}
+}
+
+type connConfig struct {
+	host    string
+	port    int
+	timeout time.Duration
+}
+
+var benchConfigs = []connConfig{
+	{host: "db-1.local", port: 5432, timeout: 3 * time.Second},
+	{host: "db-2.local", port: 5433, timeout: 1500 * time.Millisecond},
+}
+
+func BenchmarkNewPairs(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := benchConfigs[i%len(benchConfigs)]
+		_ = errm.New("cannot connect", "host", c.host, "port", c.port, "timeout", c.timeout)
+	}
+}
+
+func BenchmarkNewFieldValues(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := benchConfigs[i%len(benchConfigs)]
+		_ = errm.New("cannot connect", errm.String("host", c.host), errm.Int("port", c.port), errm.Dur("timeout", c.timeout))
+	}
+}
+
+func BenchmarkNewFields(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := benchConfigs[i%len(benchConfigs)]
+		_ = errm.NewFields("cannot connect", errm.String("host", c.host), errm.Int("port", c.port), errm.Dur("timeout", c.timeout))
+	}
+}
@@ -0,0 +1,117 @@
+package errm
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// Fingerprint returns a stable hash of the error that can be used to group errors, e.g. "3f1c0a9b2e8d7c64".
+// It is built from message templates of every layer without field values and format arguments,
+// from field keys and codes, from types of external errors and from function names of the stack where the error was created,
+// so it doesn't change with field values and line numbers between deploys.
+// Fingerprints of [List] and [Set] errors and causes of [WrapMany] are built from fingerprints of their errors.
+// It returns an empty string for a nil error.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+	h := fnv.New64a()
+	writeFingerprint(h, err)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func writeFingerprint(h hash.Hash64, err error) {
+	if errs, ok := collectorErrors(err); ok {
+		writeParts(h, "collector")
+		writeFingerprints(h, errs)
+		return
+	}
+
+	e, ok := err.(errorImpl)
+	if !ok || e.top == nil {
+		writeExternalFingerprint(h, err)
+		return
+	}
+	for l := e.top; l != nil; l = l.next {
+		writeParts(h, "layer", l.template())
+		if l.code != "" {
+			writeParts(h, "code", string(l.code))
+		}
+		l.eachField(func(f Field) {
+			writeParts(h, f.Key)
+		})
+		if l.joined {
+			writeParts(h, "causes")
+			writeFingerprints(h, l.causes)
+		}
+		if l.cause != nil {
+			writeFingerprint(h, l.cause)
+		}
+	}
+	for _, frame := range eris.Unpack(e.err).ErrRoot.Stack {
+		if isInternalFrame(frame.Name) {
+			continue
+		}
+		writeParts(h, "frame", frame.Name)
+	}
+}
+
+// writeFingerprints writes sorted fingerprints of errors, so their order doesn't matter.
+func writeFingerprints(h hash.Hash64, errs []error) {
+	prints := make([]string, len(errs))
+	for i, err := range errs {
+		prints[i] = Fingerprint(err)
+	}
+	slices.Sort(prints)
+	writeParts(h, prints...)
+}
+
+// writeExternalFingerprint writes types of the error chain and the message of the last error,
+// because messages of wrappers usually contain values.
+func writeExternalFingerprint(h hash.Hash64, err error) {
+	for {
+		writeParts(h, "external", reflect.TypeOf(err).String())
+		next := errors.Unwrap(err)
+		if next == nil {
+			writeParts(h, err.Error())
+			return
+		}
+		if _, ok := next.(errorImpl); ok {
+			writeFingerprint(h, next)
+			return
+		}
+		err = next
+	}
+}
+
+func writeParts(h hash.Hash64, parts ...string) {
+	for _, p := range parts {
+		_, _ = h.Write([]byte(p))
+		_, _ = h.Write([]byte{0})
+	}
+}
+
+// collectorErrors returns errors of [List] and sets if err is one of them.
+func collectorErrors(err error) ([]error, bool) {
+	switch e := err.(type) {
+	case listError:
+		return e.errs, true
+	case setError:
+		return e.Errors(), true
+	case compactSetError:
+		return e.Errors(), true
+	}
+	return nil, false
+}
+
+// isInternalFrame returns true for frames of runtime and this package, they don't depend on the caller.
+func isInternalFrame(name string) bool {
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "errm.")
+}
@@ -0,0 +1,94 @@
+package errm_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func findUser(id int) error {
+	return errm.Wrapf(errm.New("not found", "id", id), "find user in %s", fmt.Sprint("table", id), "id", id)
+}
+
+func findOrder(id int) error {
+	return errm.Wrapf(errm.New("not found", "id", id), "find user in %s", fmt.Sprint("table", id), "id", id)
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := errm.Fingerprint(findUser(1))
+	if len(fp) != 16 {
+		t.Errorf("expected 16 hex chars, got %s", fp)
+	}
+	if got := errm.Fingerprint(findUser(2)); got != fp {
+		t.Errorf("expected the same fingerprint for different values, got %s and %s", fp, got)
+	}
+	if got := errm.Fingerprint(findOrder(1)); got == fp {
+		t.Errorf("expected different fingerprints for different call sites, got %s", got)
+	}
+	if got := errm.Fingerprint(errm.Wrap(findUser(1), "handler")); got == fp {
+		t.Errorf("expected different fingerprints for different layers, got %s", got)
+	}
+
+	a := errm.New("not found", "id", 1)
+	b := errm.New("not found", "id", 2)
+	c := errm.New("not found", "name", 2)
+	if errm.Fingerprint(a) != errm.Fingerprint(b) {
+		t.Errorf("expected the same fingerprint for errors from the same function")
+	}
+	if errm.Fingerprint(a) == errm.Fingerprint(c) {
+		t.Errorf("expected different fingerprints for different field keys")
+	}
+
+	_, errOpen1 := os.Open("/not/existing/1")
+	_, errOpen2 := os.Open("/not/existing/2")
+	if !errors.Is(errOpen1, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error, got %s", errOpen1)
+	}
+	if errm.Fingerprint(errm.Wrap(errOpen1, "open")) != errm.Fingerprint(errm.Wrap(errOpen2, "open")) {
+		t.Errorf("expected the same fingerprint for external errors of the same type")
+	}
+
+	if errm.Fingerprint(nil) != "" {
+		t.Errorf("expected empty fingerprint for nil")
+	}
+}
+
+func TestFingerprintCollectors(t *testing.T) {
+	list1, list2 := errm.NewList(), errm.NewList()
+	for i := 0; i < 2; i++ {
+		list1.Add(findUser(i))
+		list1.Add(findOrder(i))
+		list2.Add(findOrder(i + 10))
+		list2.Add(findUser(i + 10))
+	}
+	if errm.Fingerprint(list1.Err()) != errm.Fingerprint(list2.Err()) {
+		t.Errorf("expected the same fingerprint for lists with the same errors")
+	}
+
+	s := errm.NewSetWithKey(errm.KeyByFingerprint)
+	for i := 0; i < 10; i++ {
+		s.Add(findUser(i))
+		s.Add(findOrder(i))
+	}
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+}
+
+func TestFingerprintWrapMany(t *testing.T) {
+	errA := errm.New("timeout")
+	errB := errors.New("connection refused")
+
+	first := errm.Fingerprint(errm.WrapMany([]error{errA, errB}, "cannot replicate"))
+	second := errm.Fingerprint(errm.WrapMany([]error{errB, errA}, "cannot replicate"))
+	if first != second {
+		t.Errorf("expected the same fingerprint for different order of causes, got %s and %s", first, second)
+	}
+	if other := errm.Fingerprint(errm.WrapMany([]error{errA}, "cannot replicate")); other == first {
+		t.Errorf("expected different fingerprints for different causes")
+	}
+}
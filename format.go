@@ -0,0 +1,40 @@
+package errm
+
+import (
+	"strings"
+
+	"github.com/maxbolgarin/errm/internal/fmtscan"
+)
+
+// wrapVerbs returns errors that are operands of %w verbs and the format where these verbs are replaced with %v,
+// because fmt.Sprintf doesn't support %w. Verbs %w with operands that are not errors stay as they are,
+// so they are rendered like fmt.Errorf does it.
+func wrapVerbs(format string, args []any) (string, []error) {
+	if !strings.Contains(format, "%") {
+		return format, nil
+	}
+	verbs, _ := fmtscan.Scan(format)
+
+	var (
+		causes []error
+		out    []byte
+	)
+	for _, v := range verbs {
+		if v.Verb != 'w' || v.Arg < 0 || v.Arg >= len(args) {
+			continue
+		}
+		err, ok := args[v.Arg].(error)
+		if !ok || err == nil {
+			continue
+		}
+		if out == nil {
+			out = []byte(format)
+		}
+		out[v.Pos] = 'v'
+		causes = append(causes, err)
+	}
+	if out == nil {
+		return format, nil
+	}
+	return string(out), causes
+}
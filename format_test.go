@@ -0,0 +1,113 @@
+package errm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestFormatArgsNumber(t *testing.T) {
+	testCases := []struct {
+		format string
+		exp    int
+	}{
+		{"", 0},
+		{"no verbs", 0},
+		{"%d", 1},
+		{"%s %d", 2},
+		{"%%", 0},
+		{"100%%", 0},
+		{"%% %d %%", 1},
+		{"%%%d", 1},
+		{"%d%%", 1},
+		{"%v %+v %#v %T %q %x %X %p", 8},
+		{"%-5d|%05d|%+d|% d|%#x", 5},
+		{"%5.2f", 1},
+		{"%.2f", 1},
+		{"%.f", 1},
+		{"%*d", 2},
+		{"%-*d", 2},
+		{"%.*f", 2},
+		{"%*.*f", 3},
+		{"%[1]d", 1},
+		{"%[2]d", 2},
+		{"%[2]d %[1]d", 2},
+		{"%[1]d %d", 2},
+		{"%[3]d %d", 4},
+		{"%[2]d %[2]d", 2},
+		{"%[3]*.[2]*[1]f", 3},
+		{"%[2]*d", 3},
+		{"%d %d %[1]d", 2},
+		{"%[0]d", 0},
+		{"%[x]d", 0},
+		{"%[1d", 0},
+		{"%[]d", 0},
+		{"%[3]2d", 0},
+		{"%[3].2d", 0},
+		{"%", 0},
+		{"%-", 0},
+		{"abc %", 0},
+		{"% d", 1},
+		{"50% done", 1},
+		{"%!", 1},
+		{"%ä", 1},
+		{"%w", 1},
+		{"%w: %w", 2},
+		{"%d %", 1},
+		{"%.", 1},
+		{"%5", 0},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.format, func(t *testing.T) {
+			if got := errm.FormatArgsNumber(test.format); got != test.exp {
+				t.Errorf("expected %d, got %d", test.exp, got)
+			}
+
+			// Check the number against the fmt package itself.
+			args := make([]any, test.exp+1)
+			for i := range args {
+				args[i] = i + 1
+			}
+			if out := fmt.Sprintf(test.format, args[:test.exp]...); strings.Contains(out, "(MISSING)") {
+				t.Errorf("fmt needs more operands than %d: %s", test.exp, out)
+			}
+			if strings.Contains(test.format, "[") {
+				return
+			}
+			if out := fmt.Sprintf(test.format, args...); !strings.Contains(out, "%!(EXTRA") {
+				t.Errorf("fmt uses more operands than %d: %s", test.exp, out)
+			}
+		})
+	}
+}
+
+func TestErrorfFields(t *testing.T) {
+	testCases := []struct {
+		format string
+		args   []any
+		exp    string
+	}{
+		{"100%%", []any{"field", 1}, "100% field=1"},
+		{"%d%% of %s", []any{50, "disk", "host", "db"}, "50% of disk host=db"},
+		{"%[2]s %[1]s", []any{"a", "b", "field", 1}, "b a field=1"},
+		{"%*d|", []any{4, 7, "field", 1}, "   7| field=1"},
+		{"%.*f", []any{2, 3.14159, "field", 1}, "3.14 field=1"},
+		{"%s", []any{"a", "field", "100%d"}, "a field=100%d"},
+		{"no verbs", []any{"field", "100%"}, "no verbs field=100%"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.format, func(t *testing.T) {
+			if got := errm.Errorf(test.format, test.args...).Error(); got != test.exp {
+				t.Errorf("expected %s, got %s", test.exp, got)
+			}
+			exp := "wrap " + test.exp + ": base"
+			if got := errm.Wrapf(errm.New("base"), "wrap "+test.format, test.args...).Error(); got != exp {
+				t.Errorf("expected %s, got %s", exp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Package fmtscan parses printf formats the same way the fmt package does.
+// It is shared by the errm package and the errmvet analyzer.
+package fmtscan
+
+import "unicode/utf8"
+
+// Verb is a verb of a printf format with the index of its operand.
+type Verb struct {
+	Verb rune
+	Pos  int // position of the verb in the format
+	Arg  int // index of the operand, it is -1 for verbs with a bad argument index
+}
+
+// Scan parses a printf format following the rules of the fmt package and
+// returns verbs that take operands and the number of operands the format uses,
+// including '*' width and precision operands and explicit argument indexes like %[2]d.
+// Operands after that number are not used by the format.
+func Scan(format string) (verbs []Verb, argsNum int) {
+	argNum := 0
+	use := func() {
+		argNum++
+		argsNum = max(argsNum, argNum)
+	}
+
+	end := len(format)
+	for i := 0; i < end; {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		i++
+
+		// Flags.
+	flags:
+		for ; i < end; i++ {
+			switch format[i] {
+			case '#', '0', '+', '-', ' ':
+			default:
+				break flags
+			}
+		}
+
+		goodArgNum := true
+		var afterIndex bool
+
+		// Argument index and width.
+		argNum, i, afterIndex, goodArgNum = argNumber(argNum, format, i, goodArgNum)
+		if i < end && format[i] == '*' {
+			i++
+			use()
+			afterIndex = false
+		} else {
+			start := i
+			i = skipNumber(format, i)
+			if afterIndex && i > start {
+				goodArgNum = false
+			}
+		}
+
+		// Precision.
+		if i+1 < end && format[i] == '.' {
+			i++
+			if afterIndex {
+				goodArgNum = false
+			}
+			argNum, i, afterIndex, goodArgNum = argNumber(argNum, format, i, goodArgNum)
+			if i < end && format[i] == '*' {
+				i++
+				use()
+				afterIndex = false
+			} else {
+				i = skipNumber(format, i)
+			}
+		}
+
+		if !afterIndex {
+			argNum, i, _, goodArgNum = argNumber(argNum, format, i, goodArgNum)
+		}
+
+		if i >= end {
+			break
+		}
+
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		pos := i
+		i += size
+
+		switch {
+		case verb == '%':
+			// Percent does not absorb operands.
+		case !goodArgNum:
+			verbs = append(verbs, Verb{Verb: verb, Pos: pos, Arg: -1})
+		default:
+			verbs = append(verbs, Verb{Verb: verb, Pos: pos, Arg: argNum})
+			use()
+		}
+	}
+	return verbs, argsNum
+}
+
+// argNumber returns the operand index of an explicit argument index like [3] at format[i:] if there is one.
+// The number of operands is not known, so any positive index is valid.
+func argNumber(argNum int, format string, i int, goodArgNum bool) (int, int, bool, bool) {
+	if i >= len(format) || format[i] != '[' {
+		return argNum, i, false, goodArgNum
+	}
+	for j := i + 1; j < len(format); j++ {
+		if format[j] != ']' {
+			continue
+		}
+		n, ok := parseNumber(format[i+1 : j])
+		if !ok || n < 1 {
+			return argNum, j + 1, false, false
+		}
+		return n - 1, j + 1, true, goodArgNum
+	}
+	return argNum, i + 1, false, false
+}
+
+func parseNumber(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return 0, false
+		}
+		n = n*10 + int(s[i]-'0')
+		if n > 1e6 {
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+func skipNumber(format string, i int) int {
+	for i < len(format) && isDigit(format[i]) {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,578 @@
+package errm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rotisserie/eris"
+)
+
+// JSONVersion is the version of the JSON schema used by [Encode], it changes only with incompatible changes of the schema.
+const JSONVersion = 1
+
+// Kinds of errors in the JSON schema, see [Encode].
+const (
+	jsonKindError    = "error"
+	jsonKindList     = "list"
+	jsonKindSet      = "set"
+	jsonKindExternal = "external"
+)
+
+// jsonError is an error in the JSON schema, see [Encode] for the description of its members.
+type jsonError struct {
+	Version int         `json:"version,omitempty"`
+	Kind    string      `json:"kind"`
+	Message string      `json:"message"`
+	Code    Code        `json:"code,omitempty"`
+	Type    string      `json:"type,omitempty"`
+	Count   int         `json:"count,omitempty"`
+	Layers  []jsonLayer `json:"layers,omitempty"`
+	Causes  []jsonError `json:"causes,omitempty"`
+	Errors  []jsonError `json:"errors,omitempty"`
+	Stack   []Frame     `json:"stack,omitempty"`
+}
+
+// jsonLayer is a single message of an error chain in the JSON schema.
+type jsonLayer struct {
+	Message  string      `json:"message"`
+	Template string      `json:"template,omitempty"`
+	Fields   jsonFields  `json:"fields,omitempty"`
+	Code     Code        `json:"code,omitempty"`
+	Causes   []jsonError `json:"causes,omitempty"`
+	Joined   bool        `json:"joined,omitempty"`
+	Cause    *jsonError  `json:"cause,omitempty"`
+	Attached bool        `json:"attached,omitempty"`
+	Public   string      `json:"public,omitempty"`
+}
+
+// Frame is a frame of a stack trace in the JSON schema, see [Encode] and [RemoteStack].
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// jsonFields are fields of a layer that are encoded as a JSON object keeping their order.
+type jsonFields []Field
+
+// MarshalJSON implements [json.Marshaler].
+func (fs jsonFields) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for i, f := range fs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		key, _ := json.Marshal(f.Key)
+		b = append(b, key...)
+		b = append(b, ':')
+		b = append(b, jsonValue(f)...)
+	}
+	return append(b, '}'), nil
+}
+
+// UnmarshalJSON implements [json.Unmarshaler] keeping the order of fields.
+// Strings, bools and numbers are decoded as typed fields, other values are decoded using encoding/json.
+// Null is decoded as no fields.
+func (fs *jsonFields) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if t != json.Delim('{') {
+		return fmt.Errorf("fields must be an object, got %v", t)
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := t.(string)
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		*fs = append(*fs, decodeField(key, value))
+	}
+	_, err := dec.Token()
+	return err
+}
+
+func decodeField(key string, value any) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case bool:
+		return Bool(key, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return Int64(key, i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return Float64(key, f)
+		}
+	}
+	return Any(key, value)
+}
+
+// jsonValue returns the value of the field encoded using encoding/json. Errors and durations are encoded as strings
+// as they are rendered in messages, values that cannot be encoded are encoded as their string representations.
+func jsonValue(f Field) []byte {
+	var v any
+	switch value := f.Value().(type) {
+	case error:
+		v = value.Error()
+	case time.Duration:
+		v = value.String()
+	default:
+		v = value
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		var buf [64]byte
+		raw, s := f.render(buf[:0])
+		b, _ = json.Marshal(string(raw) + s)
+	}
+	return b
+}
+
+// JSONOption configures encoding of an error by [Encode].
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	omitStack bool
+}
+
+// OmitStack makes [Encode] skip stack traces, e.g. for errors that are sent to clients.
+func OmitStack() JSONOption {
+	return func(o *jsonOptions) {
+		o.omitStack = true
+	}
+}
+
+// Encode returns the error encoded as a JSON object of the documented schema, it returns "null" for a nil error.
+// Errors of this package, [List], [Set] and [CompactSet] errors implement [json.Marshaler] using Encode
+// with a stack trace, so you can put them into structures that are encoded using encoding/json.
+//
+// Every error is an object with the following members, empty members are omitted:
+//
+//   - "version" is [JSONVersion], it is present only in the outermost object;
+//   - "kind" is "error" for errors of this package, "list" for [List], "set" for [Set] and [CompactSet]
+//     and "external" for other errors;
+//   - "message" is the result of the Error method;
+//   - "code" is the result of [CodeOf];
+//   - "type" is the Go type of an external error, e.g. "*fs.PathError";
+//   - "count" is the number of occurrences of an error in a set;
+//   - "layers" are messages of an error chain starting from the outermost one, see below;
+//   - "causes" are errors wrapped by an external error, e.g. using fmt.Errorf with %w;
+//   - "errors" are errors of a list or a set in the same order as they are rendered;
+//   - "stack" is a stack trace where the error was created without frames of the runtime and this package,
+//     every frame has "function", "file" and "line" members.
+//
+// Every layer is an object with the following members:
+//
+//   - "message" is the message without fields;
+//   - "template" is the format of [Errorf] or the template of [T] before formatting;
+//   - "fields" is an object with fields in the order they were provided, keys may repeat as they do in messages.
+//     Values are encoded using encoding/json, errors and durations are encoded as strings as in messages;
+//   - "code" is the code of the layer;
+//   - "causes" are errors wrapped using %w verbs or [WrapMany];
+//   - "joined" is true if the causes are rendered after the message, see [WrapMany];
+//   - "cause" is an external error wrapped by the innermost layer;
+//   - "attached" is true if the layer adds fields to the external cause using [With] and has its message;
+//   - "public" is the public message of the layer, see [WithPublic].
+//
+// An example of an error created by errm.Wrap(errm.New("not found", errm.NotFound, "id", 42), "cannot load"):
+//
+//	{
+//	  "version": 1,
+//	  "kind": "error",
+//	  "message": "cannot load: not found id=42",
+//	  "code": "not_found",
+//	  "layers": [
+//	    {"message": "cannot load"},
+//	    {"message": "not found", "fields": {"id": 42}, "code": "not_found"}
+//	  ],
+//	  "stack": [{"function": "main.load", "file": "/app/main.go", "line": 12}]
+//	}
+func Encode(err error, opts ...JSONOption) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	var o jsonOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	out := o.encode(err)
+	out.Version = JSONVersion
+	return json.Marshal(out)
+}
+
+func (o *jsonOptions) encode(err error) jsonError {
+	out := jsonError{Message: err.Error(), Code: CodeOf(err)}
+
+	switch e := err.(type) {
+	case listError:
+		out.Kind = jsonKindList
+		out.Errors = o.encodeAll(e.errs)
+		return out
+	case setError:
+		out.Kind = jsonKindSet
+		out.Errors = o.encodeEntries(e.ordered())
+		return out
+	case compactSetError:
+		out.Kind = jsonKindSet
+		out.Errors = o.encodeEntries(e.Entries())
+		return out
+	case errorImpl:
+		if e.top == nil {
+			break
+		}
+		out.Kind = jsonKindError
+		for l := e.top; l != nil; l = l.next {
+			out.Layers = append(out.Layers, o.encodeLayer(l))
+		}
+		if o.omitStack {
+			return out
+		}
+		if remote, ok := e.err.(*remoteStack); ok {
+			out.Stack = remote.stack
+			return out
+		}
+		for _, frame := range eris.Unpack(e.err).ErrRoot.Stack {
+			if isInternalFrame(frame.Name) {
+				continue
+			}
+			out.Stack = append(out.Stack, Frame{Function: frame.Name, File: frame.File, Line: frame.Line})
+		}
+		return out
+	}
+
+	out.Kind = jsonKindExternal
+	out.Type = fmt.Sprintf("%T", err)
+	if remote, ok := err.(*remoteError); ok {
+		out.Type = remote.typ
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		out.Causes = o.encodeAll(e.Unwrap())
+	default:
+		if cause := errors.Unwrap(err); cause != nil {
+			out.Causes = []jsonError{o.encode(cause)}
+		}
+	}
+	return out
+}
+
+func (o *jsonOptions) encodeLayer(l *layer) jsonLayer {
+	out := jsonLayer{
+		Message:  l.msg,
+		Template: l.format,
+		Code:     l.code,
+		Causes:   o.encodeAll(l.causes),
+		Joined:   l.joined,
+		Attached: l.attached,
+		Public:   l.public,
+	}
+	add := func(f Field) {
+		out.Fields = append(out.Fields, f)
+	}
+	forEachField(l.inline, add)
+	l.eachField(add)
+	if l.cause != nil {
+		cause := o.encode(l.cause)
+		out.Cause = &cause
+	}
+	return out
+}
+
+func (o *jsonOptions) encodeAll(errs []error) []jsonError {
+	if len(errs) == 0 {
+		return nil
+	}
+	out := make([]jsonError, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			out = append(out, o.encode(err))
+		}
+	}
+	return out
+}
+
+func (o *jsonOptions) encodeEntries(entries []SetEntry) []jsonError {
+	out := make([]jsonError, len(entries))
+	for i, entry := range entries {
+		out[i] = o.encode(entry.Err)
+		out[i].Count = entry.Count
+	}
+	return out
+}
+
+// MarshalJSON implements [json.Marshaler] using [Encode].
+func (e errorImpl) MarshalJSON() ([]byte, error) {
+	return Encode(e)
+}
+
+// MarshalJSON implements [json.Marshaler] using [Encode].
+func (e listError) MarshalJSON() ([]byte, error) {
+	return Encode(e)
+}
+
+// MarshalJSON implements [json.Marshaler] using [Encode].
+func (e setError) MarshalJSON() ([]byte, error) {
+	return Encode(e)
+}
+
+// MarshalJSON implements [json.Marshaler] using [Encode].
+func (e compactSetError) MarshalJSON() ([]byte, error) {
+	return Encode(e)
+}
+
+// sentinels are errors registered using [Register] by their messages.
+var sentinels sync.Map
+
+// Register registers sentinel errors for [Decode], so decoded errors with the same messages are replaced by them
+// and [Is] and [errors.Is] find them, e.g.
+//
+//	var ErrNotFound = errm.New("not found", errm.NotFound)
+//
+//	func init() {
+//		errm.Register(ErrNotFound, sql.ErrNoRows)
+//	}
+//
+// Errors are matched by the result of the Error method, so an error wrapped using errm.Wrap(ErrNotFound, "cannot load")
+// on the sending side is decoded as errm.Wrap(ErrNotFound, "cannot load") with the registered ErrNotFound.
+// Errors with fields added using [With] or in a wrapping layer don't match their sentinels.
+// It is safe for concurrent/parallel usage.
+func Register(errs ...error) {
+	for _, err := range errs {
+		if err != nil {
+			sentinels.Store(err.Error(), err)
+		}
+	}
+}
+
+func sentinel(msg string) (error, bool) {
+	err, ok := sentinels.Load(msg)
+	if !ok {
+		return nil, false
+	}
+	return err.(error), true
+}
+
+// Decode decodes an error encoded using [Encode]. It returns nil for "null" and an error if the data is not a valid
+// error of the supported [JSONVersion]. The decoded error has the same message, layers with fields and codes, causes,
+// and errors of lists and sets as the encoded one. External errors keep their messages and causes,
+// errors registered using [Register] are replaced by the registered ones.
+// The stack trace of the encoded error is available using [RemoteStack], the decoded error has no own stack trace.
+func Decode(data []byte) (error, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil, nil
+	}
+	var in jsonError
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, Wrap(err, "cannot decode error")
+	}
+	if in.Version != JSONVersion {
+		return nil, New("unsupported version of encoded error", "version", in.Version, "supported", JSONVersion)
+	}
+	if err := validate(in); err != nil {
+		return nil, Wrap(err, "invalid encoded error")
+	}
+	return decode(in), nil
+}
+
+// validate returns an error if the error or one of its causes and members has no known kind,
+// e.g. it is null, or it has the error kind without layers.
+func validate(in jsonError) error {
+	switch in.Kind {
+	case jsonKindError:
+		if len(in.Layers) == 0 {
+			return New("error has no layers")
+		}
+	case jsonKindList, jsonKindSet, jsonKindExternal:
+	case "":
+		return New("error has no kind")
+	default:
+		return New("unknown kind of error", "kind", in.Kind)
+	}
+	for _, l := range in.Layers {
+		if l.Cause != nil {
+			if err := validate(*l.Cause); err != nil {
+				return err
+			}
+		}
+		for _, cause := range l.Causes {
+			if err := validate(cause); err != nil {
+				return err
+			}
+		}
+	}
+	for _, member := range slices.Concat(in.Causes, in.Errors) {
+		if err := validate(member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decode(in jsonError) error {
+	switch in.Kind {
+	case jsonKindError:
+		if len(in.Layers) > 0 {
+			return decodeLayers(in)
+		}
+	case jsonKindList:
+		list := NewListWithCapacity(len(in.Errors))
+		for _, member := range in.Errors {
+			list.Add(decode(member))
+		}
+		return list.Err()
+	case jsonKindSet:
+		set := NewSetWithCapacity(len(in.Errors))
+		for _, member := range in.Errors {
+			err := decode(member)
+			if err == nil {
+				continue
+			}
+			set.Add(err)
+			set.entries[set.index[set.key(err)]].Count += max(member.Count, 1) - 1
+		}
+		return set.Err()
+	}
+	if err, ok := sentinel(in.Message); ok {
+		return err
+	}
+	return &remoteError{msg: in.Message, typ: in.Type, code: in.Code, errs: decodeAll(in.Causes)}
+}
+
+// decodeLayers builds layers of an error starting from the innermost one,
+// so a sentinel matching the innermost layers replaces them.
+func decodeLayers(in jsonError) error {
+	var (
+		next  *layer
+		cause error // external error wrapped by the innermost layer
+		chain error
+	)
+	if root := in.Layers[len(in.Layers)-1]; root.Cause != nil {
+		cause = decode(*root.Cause)
+	}
+	for i := len(in.Layers) - 1; i >= 0; i-- {
+		l := decodeLayer(in.Layers[i])
+		l.next = next
+		msg := l.render()
+		if next == nil {
+			l.cause = cause
+			if l.attached {
+				msg = ""
+			}
+			chain = &plainError{msg: msg, err: cause}
+		} else {
+			chain = &plainError{msg: msg, err: chain}
+		}
+
+		s, ok := sentinel(errorImpl{err: chain, top: l}.Error())
+		if !ok {
+			next = l
+			continue
+		}
+		if e, ok := s.(errorImpl); ok && e.top != nil {
+			next, cause, chain = e.top, nil, e.err
+		} else {
+			next, cause, chain = nil, s, nil
+		}
+	}
+
+	if next == nil {
+		return cause
+	}
+	if len(in.Stack) > 0 {
+		chain = &remoteStack{err: chain, stack: in.Stack}
+	}
+	return errorImpl{err: chain, top: next}
+}
+
+func decodeLayer(in jsonLayer) *layer {
+	l := &layer{
+		msg:      in.Message,
+		code:     in.Code,
+		causes:   decodeAll(in.Causes),
+		joined:   in.Joined,
+		attached: in.Attached,
+		public:   in.Public,
+	}
+	fields := make([]any, len(in.Fields))
+	for i, f := range in.Fields {
+		fields[i] = f
+	}
+	l.fields = fields
+	if in.Template == "" {
+		return l
+	}
+	// Templates of T are rendered to the message, formats of Errorf are not.
+	if msg, inline, rest, _ := renderTemplate(in.Template, fields); msg == in.Message && len(inline) > 0 {
+		l.inline, l.fields = inline, rest
+	}
+	l.format = in.Template
+	return l
+}
+
+func decodeAll(in []jsonError) []error {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]error, 0, len(in))
+	for _, member := range in {
+		if err := decode(member); err != nil {
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+// RemoteStack returns the stack trace of an error decoded using [Decode] that was encoded on the sending side.
+// It returns nil for other errors and errors encoded without a stack trace.
+func RemoteStack(err error) []Frame {
+	var remote *remoteStack
+	if !errors.As(err, &remote) {
+		return nil
+	}
+	return slices.Clone(remote.stack)
+}
+
+// remoteStack keeps the stack trace of a decoded error in its chain, see [RemoteStack].
+type remoteStack struct {
+	err   error
+	stack []Frame
+}
+
+func (e *remoteStack) Error() string {
+	return e.err.Error()
+}
+
+func (e *remoteStack) Unwrap() error {
+	return e.err
+}
+
+// remoteError is a decoded external error, it keeps the message, the type name, the code and causes of the original error.
+type remoteError struct {
+	msg  string
+	typ  string
+	code Code
+	errs []error
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Unwrap() []error {
+	return e.errs
+}
@@ -0,0 +1,233 @@
+package errm_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestEncode(t *testing.T) {
+	notFound := errm.New("not found", errm.NotFound, "id", 42)
+
+	set := errm.NewSet()
+	set.New("timeout", "host", "db")
+	set.New("timeout", "host", "db")
+	set.New("refused")
+
+	list := errm.NewList()
+	list.Add(notFound)
+	list.Add(io.EOF)
+
+	testCases := []struct {
+		id  string
+		err error
+		exp string
+	}{
+		{id: "nil", err: nil, exp: `null`},
+		{
+			id:  "new",
+			err: notFound,
+			exp: `{"version":1,"kind":"error","message":"not found id=42","code":"not_found","layers":[{"message":"not found","fields":{"id":42},"code":"not_found"}]}`,
+		},
+		{
+			id:  "wrap",
+			err: errm.Wrap(notFound, "cannot load", "table", "users"),
+			exp: `{"version":1,"kind":"error","message":"cannot load table=users: not found id=42","code":"not_found","layers":[{"message":"cannot load","fields":{"table":"users"}},{"message":"not found","fields":{"id":42},"code":"not_found"}]}`,
+		},
+		{
+			id:  "wrap_external",
+			err: errm.Wrap(io.EOF, "cannot read"),
+			exp: `{"version":1,"kind":"error","message":"cannot read: EOF","layers":[{"message":"cannot read","cause":{"kind":"external","message":"EOF","type":"*errors.errorString"}}]}`,
+		},
+		{
+			id:  "errorf",
+			err: errm.Errorf("user %d: %w", 5, io.EOF, "retry", true),
+			exp: `{"version":1,"kind":"error","message":"user 5: EOF retry=true","layers":[{"message":"user 5: EOF","template":"user %d: %w","fields":{"retry":true},"causes":[{"kind":"external","message":"EOF","type":"*errors.errorString"}]}]}`,
+		},
+		{
+			id:  "template",
+			err: errm.T("user {id} not found", "id", 5, "table", "users"),
+			exp: `{"version":1,"kind":"error","message":"user 5 not found table=users","layers":[{"message":"user 5 not found","template":"user {id} not found","fields":{"id":5,"table":"users"}}]}`,
+		},
+		{
+			id:  "wrap_many",
+			err: errm.WrapMany([]error{io.EOF, notFound}, "sync"),
+			exp: `{"version":1,"kind":"error","message":"sync: [EOF; not found id=42]","layers":[{"message":"sync","causes":[{"kind":"external","message":"EOF","type":"*errors.errorString"},{"kind":"error","message":"not found id=42","code":"not_found","layers":[{"message":"not found","fields":{"id":42},"code":"not_found"}]}],"joined":true}]}`,
+		},
+		{
+			id:  "with_external",
+			err: errm.With(io.EOF, "file", "a.txt"),
+			exp: `{"version":1,"kind":"error","message":"EOF file=a.txt","layers":[{"message":"EOF","fields":{"file":"a.txt"},"cause":{"kind":"external","message":"EOF","type":"*errors.errorString"},"attached":true}]}`,
+		},
+		{
+			id:  "external_wrapping",
+			err: fmt.Errorf("load: %w", notFound),
+			exp: `{"version":1,"kind":"external","message":"load: not found id=42","code":"not_found","type":"*fmt.wrapError","causes":[{"kind":"error","message":"not found id=42","code":"not_found","layers":[{"message":"not found","fields":{"id":42},"code":"not_found"}]}]}`,
+		},
+		{
+			id:  "list",
+			err: list.Err(),
+			exp: `{"version":1,"kind":"list","message":"not found id=42; EOF","errors":[{"kind":"error","message":"not found id=42","code":"not_found","layers":[{"message":"not found","fields":{"id":42},"code":"not_found"}]},{"kind":"external","message":"EOF","type":"*errors.errorString"}]}`,
+		},
+		{
+			id:  "set",
+			err: set.Err(),
+			exp: `{"version":1,"kind":"set","message":"timeout host=db (x2); refused","errors":[{"kind":"error","message":"timeout host=db","count":2,"layers":[{"message":"timeout","fields":{"host":"db"}}]},{"kind":"error","message":"refused","count":1,"layers":[{"message":"refused"}]}]}`,
+		},
+		{
+			id:  "values",
+			err: errm.New("x", "err", io.EOF, "dur", 1500*time.Millisecond, errm.Dur("typed", time.Second), "nan", math.NaN(), "map", map[string]int{"a": 1}, "key"),
+			exp: `{"version":1,"kind":"error","message":"x err=EOF dur=1.5s typed=1s nan=NaN map=map[a:1] key=!MISSING","layers":[{"message":"x","fields":{"err":"EOF","dur":"1.5s","typed":"1s","nan":"NaN","map":{"a":1},"key":"!MISSING"}}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			data, err := errm.Encode(tc.err, errm.OmitStack())
+			if err != nil {
+				t.Fatalf("expected nil, got %s", err)
+			}
+			if string(data) != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, data)
+			}
+
+			decoded, err := errm.Decode(data)
+			if err != nil {
+				t.Fatalf("expected nil, got %s", err)
+			}
+			if tc.err != nil && decoded.Error() != tc.err.Error() {
+				t.Errorf("expected %s, got %s", tc.err, decoded)
+			}
+			if again, _ := errm.Encode(decoded, errm.OmitStack()); string(again) != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, again)
+			}
+		})
+	}
+}
+
+func TestEncodeStack(t *testing.T) {
+	err := errm.Wrap(errm.New("not found"), "cannot load")
+	data, encodeErr := json.Marshal(struct {
+		Err error `json:"err"`
+	}{Err: err})
+	if encodeErr != nil {
+		t.Fatalf("expected nil, got %s", encodeErr)
+	}
+
+	var out struct {
+		Err struct {
+			Version int
+			Message string
+			Stack   []struct {
+				Function string
+				File     string
+				Line     int
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("expected nil, got %s", err)
+	}
+	if out.Err.Version != errm.JSONVersion || out.Err.Message != err.Error() {
+		t.Errorf("expected version %d and message %q, got %s", errm.JSONVersion, err, data)
+	}
+	if len(out.Err.Stack) == 0 || !strings.HasSuffix(out.Err.Stack[0].Function, "TestEncodeStack") ||
+		!strings.HasSuffix(out.Err.Stack[0].File, "json_test.go") || out.Err.Stack[0].Line == 0 {
+		t.Errorf("expected a stack trace of the test, got %s", data)
+	}
+
+	plain := errm.NewFactory(errm.StackTrace(errm.StackNone)).New("x")
+	if data, _ := json.Marshal(plain); strings.Contains(string(data), `"stack"`) {
+		t.Errorf("expected no stack trace, got %s", data)
+	}
+
+	m := errm.ToJSON(err, errm.OmitStack())
+	if m["message"] != err.Error() || m["stack"] != nil {
+		t.Errorf("expected message without stack, got %v", m)
+	}
+	if errm.ToJSON(nil) != nil {
+		t.Errorf("expected nil, got %v", errm.ToJSON(nil))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	errNotFound := errm.New("not found", errm.NotFound)
+	errm.Register(errNotFound, io.ErrUnexpectedEOF)
+
+	err := errm.Wrap(errNotFound, "cannot load", "user_id", 5)
+	data, _ := errm.Encode(err)
+	decoded, decodeErr := errm.Decode(data)
+	if decodeErr != nil {
+		t.Fatalf("expected nil, got %s", decodeErr)
+	}
+	if decoded.Error() != err.Error() {
+		t.Errorf("expected %s, got %s", err, decoded)
+	}
+	if !errm.Is(decoded, errNotFound) || !errors.Is(decoded, errNotFound) || !errm.IsCode(decoded, errm.NotFound) {
+		t.Errorf("expected true, got false")
+	}
+	if fields := errm.Fields(decoded); !reflect.DeepEqual(fields, []any{"user_id", int64(5)}) {
+		t.Errorf("expected [user_id 5], got %v", fields)
+	}
+	stack := errm.RemoteStack(decoded)
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "TestDecode") {
+		t.Errorf("expected a stack trace of the test, got %v", stack)
+	}
+	if errm.RemoteStack(err) != nil {
+		t.Errorf("expected nil, got %v", errm.RemoteStack(err))
+	}
+
+	whole, _ := errm.Decode(must(errm.Encode(errNotFound)))
+	if !errors.Is(whole, errNotFound) || !errm.Is(whole, errNotFound) {
+		t.Errorf("expected true, got false")
+	}
+	external, _ := errm.Decode(must(errm.Encode(errm.Wrapf(io.ErrUnexpectedEOF, "read %s", "a.txt"))))
+	if !errors.Is(external, io.ErrUnexpectedEOF) || external.Error() != "read a.txt: unexpected EOF" {
+		t.Errorf("expected wrapped io.ErrUnexpectedEOF, got %s", external)
+	}
+	unknown, _ := errm.Decode(must(errm.Encode(io.EOF)))
+	if errors.Is(unknown, io.EOF) || unknown.Error() != "EOF" {
+		t.Errorf("expected an unregistered error, got %#v", unknown)
+	}
+
+	if err, _ := errm.Decode([]byte(" null ")); err != nil {
+		t.Errorf("expected nil, got %s", err)
+	}
+	nullFields := `{"version":1,"kind":"error","message":"x","layers":[{"message":"x","fields":null}]}`
+	if err, decodeErr := errm.Decode([]byte(nullFields)); decodeErr != nil || err == nil || err.Error() != "x" {
+		t.Errorf("expected x, got %v, %v", err, decodeErr)
+	}
+	for _, data := range []string{
+		`{`,
+		`{"kind":"error","message":"x"}`,
+		`{"version":2,"kind":"error","message":"x"}`,
+		`{"version":1,"kind":"error","layers":[]}`,
+		`{"version":1,"kind":"list","errors":[{"kind":"error","message":"x"}]}`,
+		`{"version":1,"kind":"error","layers":[{"message":"x","cause":{"kind":"error","message":"y"}}]}`,
+		`{"version":1}`,
+		`{"version":1,"kind":"panic","message":"x"}`,
+		`{"version":1,"kind":"set","errors":[null]}`,
+		`{"version":1,"kind":"list","errors":[{"kind":"external","message":"x"},{"message":"y"}]}`,
+		`{"version":1,"kind":"error","layers":[{"message":"x","causes":[null]}]}`,
+		`{"version":1,"kind":"external","message":"x","causes":[null]}`,
+	} {
+		if _, err := errm.Decode([]byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+}
+
+func must(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,103 @@
+package errm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// KeyByMessage returns the full error message, so errors are the same if their messages are equal.
+// It is the default key of [Set].
+func KeyByMessage(err error) string {
+	return err.Error()
+}
+
+// KeyByTemplate returns the error message without values of fields, so "not found id=1" and
+// "not found id=2" are the same error. It keeps the field keys and templates of [T] instead of interpolated
+// messages. Messages of errors that were not
+// created using this package are used as is.
+func KeyByTemplate(err error) string {
+	e, ok := err.(errorImpl)
+	if !ok || e.top == nil {
+		return err.Error()
+	}
+	out := strings.Builder{}
+	for l := e.top; l != nil; l = l.next {
+		if l != e.top {
+			out.WriteString(": ")
+		}
+		if l.inline != nil {
+			out.WriteString(l.format)
+		} else {
+			out.WriteString(l.msg)
+		}
+		l.eachField(func(f Field) {
+			out.WriteRune(' ')
+			out.WriteString(f.Key)
+			out.WriteRune('=')
+		})
+		if l.joined {
+			out.WriteString(": [")
+			for i, cause := range l.causes {
+				if i > 0 {
+					out.WriteString("; ")
+				}
+				out.WriteString(KeyByTemplate(cause))
+			}
+			out.WriteRune(']')
+		}
+		if l.cause != nil && !l.attached {
+			if l.msg != "" || len(l.fields) > 0 || len(l.typed) > 0 {
+				out.WriteString(": ")
+			}
+			out.WriteString(l.cause.Error())
+		}
+	}
+	return out.String()
+}
+
+// KeyByRootCause returns the identity of the root cause of the error, so all errors that wrap
+// the same sentinel or the same error value are the same, no matter what their messages are.
+// Two different sentinels with the same message are different errors. The identity doesn't change
+// when fields or marks are added to the error with [With], [WithPublic], [MarkRetryable], [MarkPermanent]
+// or [WithSeverity].
+func KeyByRootCause(err error) string {
+	for {
+		if e, ok := err.(errorImpl); ok && e.top != nil {
+			root := e.top.root()
+			if root.cause == nil {
+				if root.origin != nil {
+					root = root.origin
+				}
+				return fmt.Sprintf("errm@%p", root)
+			}
+			err = root.cause
+			continue
+		}
+		next := errors.Unwrap(err)
+		if next == nil {
+			break
+		}
+		err = next
+	}
+	if reflect.TypeOf(err).Kind() == reflect.Pointer {
+		return fmt.Sprintf("%T@%p", err, err)
+	}
+	return fmt.Sprintf("%T:%s", err, err.Error())
+}
+
+// KeyByFingerprint returns [Fingerprint] of the error, so errors created in the same place with the same
+// message templates are the same, no matter what values their fields have.
+func KeyByFingerprint(err error) string {
+	return Fingerprint(err)
+}
+
+// KeyByCode returns [CodeOf] the error, so all errors with the same code are the same.
+// Errors without a code are compared by their messages.
+func KeyByCode(err error) string {
+	if code := CodeOf(err); code != "" {
+		return string(code)
+	}
+	return err.Error()
+}
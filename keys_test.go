@@ -0,0 +1,104 @@
+package errm_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestKeyByTemplate(t *testing.T) {
+	base := errors.New("connection refused")
+	testCases := []struct {
+		id  string
+		err error
+		exp string
+	}{
+		{
+			id:  "simple",
+			err: errm.New("not found"),
+			exp: "not found",
+		},
+		{
+			id:  "fields",
+			err: errm.New("not found", "id", 1, "table", "users"),
+			exp: "not found id= table=",
+		},
+		{
+			id:  "errorf",
+			err: errm.Errorf("not found in %s", "users", "id", 2),
+			exp: "not found in users id=",
+		},
+		{
+			id:  "wrap",
+			err: errm.Wrap(errm.New("not found", "id", 3), "get user", "request", 4),
+			exp: "get user request=: not found id=",
+		},
+		{
+			id:  "external",
+			err: errm.Wrap(base, "dial", "host", "db"),
+			exp: "dial host=: connection refused",
+		},
+		{
+			id:  "not_errm",
+			err: base,
+			exp: "connection refused",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.id, func(t *testing.T) {
+			if got := errm.KeyByTemplate(test.err); got != test.exp {
+				t.Errorf("expected %s, got %s", test.exp, got)
+			}
+		})
+	}
+}
+
+func TestSetWithKey(t *testing.T) {
+	s := errm.NewSetWithKey(errm.KeyByTemplate)
+	s.New("not found", "id", 1)
+	s.New("not found", "id", 2)
+	s.New("not found", "name", "a")
+	if s.Len() != 2 {
+		t.Errorf("expected 2, got %d", s.Len())
+	}
+
+	errA := errm.New("same message")
+	errB := errm.New("same message")
+	external := errors.New("same message")
+
+	s = errm.NewSetWithKey(errm.KeyByRootCause)
+	s.Add(errA)
+	s.Wrap(errA, "first", "id", 1)
+	s.Wrap(errm.Wrap(errA, "second"), "third")
+	s.Add(fmt.Errorf("std wrap: %w", errA))
+	if s.Len() != 1 {
+		t.Errorf("expected 1, got %d", s.Len())
+	}
+
+	for i := 0; i < 3; i++ {
+		s.Add(errm.MarkPermanent(errA))
+		s.Add(errm.With(errA, "id", i))
+		s.Add(errm.WithSeverity(errm.WithPublic(errA, "try later"), errm.LevelWarn))
+	}
+	if s.Len() != 1 {
+		t.Errorf("expected 1 after annotations, got %d", s.Len())
+	}
+
+	s.Add(errB)
+	s.Add(external)
+	s.Wrap(external, "wrapped")
+	if s.Len() != 3 {
+		t.Errorf("expected 3, got %d", s.Len())
+	}
+
+	s = errm.NewSet()
+	s.Add(errA)
+	s.Add(errB)
+	s.Add(external)
+	if s.Len() != 1 {
+		t.Errorf("expected 1, got %d", s.Len())
+	}
+}
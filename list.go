@@ -65,6 +65,11 @@ func (e *List) Has(err error, errs ...error) bool {
 	return false
 }
 
+// MaxSeverity returns the maximum [Severity] of errors in the [List] or 0 if it is empty.
+func (e *List) MaxSeverity() Level {
+	return maxSeverity(e.errs)
+}
+
 // Err returns current [List] instance as error interface or nil if it is empty.
 func (e *List) Err() error {
 	if len(e.errs) == 0 {
@@ -192,6 +197,14 @@ func (e *SafeList) Clear() {
 	e.List.Clear()
 }
 
+// MaxSeverity returns the maximum [Severity] of errors in the [SafeList] or 0 if it is empty.
+// It is safe for concurrent/parallel usage.
+func (e *SafeList) MaxSeverity() Level {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.List.MaxSeverity()
+}
+
 // Len returns the number of errors in [SafeList]. It is safe for concurrent/parallel usage.
 func (e *SafeList) Len() int {
 	e.mu.Lock()
@@ -0,0 +1,64 @@
+package errm
+
+import "errors"
+
+// WithPublic returns the error with a message that is safe to show to users, e.g. in API responses:
+//
+//	err = errm.WithPublic(err, "Order not found")
+//	errm.PublicMessage(err) // Order not found
+//
+// The public message is not rendered by Error() and the message of the error is not changed.
+// It returns nil if you provide a nil error.
+func WithPublic(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return annotate(err, func(l *layer) {
+		l.public = msg
+	})
+}
+
+// PublicMessage returns the outermost public message of the error chain set by [WithPublic].
+// If there is no public message, it returns a generic message for the code of the error, e.g. "Not found" for [NotFound],
+// or "Internal error" for errors without a known code. It never returns the result of Error(),
+// so it is safe to show it to users. It returns an empty string for a nil error.
+func PublicMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	for e := err; e != nil; {
+		impl, ok := e.(errorImpl)
+		if !ok || impl.top == nil {
+			e = errors.Unwrap(e)
+			continue
+		}
+		for l := impl.top; l != nil; l = l.next {
+			if l.public != "" {
+				return l.public
+			}
+			if l.next == nil {
+				e = l.cause
+			}
+		}
+	}
+	if msg, ok := defaultPublicMessages[CodeOf(err)]; ok {
+		return msg
+	}
+	return defaultPublicMessages[Internal]
+}
+
+// defaultPublicMessages are messages that are returned by [PublicMessage] for errors without public messages.
+var defaultPublicMessages = map[Code]string{
+	Internal:           "Internal error",
+	InvalidArgument:    "Invalid argument",
+	NotFound:           "Not found",
+	AlreadyExists:      "Already exists",
+	PermissionDenied:   "Permission denied",
+	Unauthenticated:    "Unauthenticated",
+	FailedPrecondition: "Failed precondition",
+	ResourceExhausted:  "Too many requests",
+	Canceled:           "Canceled",
+	DeadlineExceeded:   "Deadline exceeded",
+	Unavailable:        "Service unavailable",
+	Unimplemented:      "Not implemented",
+}
@@ -0,0 +1,52 @@
+package errm_test
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestPublicMessage(t *testing.T) {
+	notFound := errm.WithPublic(errm.New("no rows", errm.NotFound, "query", "SELECT * FROM orders"), "Order not found")
+
+	testCases := []struct {
+		id  string
+		err error
+		exp string
+	}{
+		{id: "nil", err: nil, exp: ""},
+		{id: "no_code", err: errm.New("dial tcp 10.0.0.1:5432: connection refused"), exp: "Internal error"},
+		{id: "external", err: io.EOF, exp: "Internal error"},
+		{id: "code", err: errm.New("no rows", errm.NotFound), exp: "Not found"},
+		{id: "custom_code", err: errm.New("x", errm.Code("quota")), exp: "Internal error"},
+		{id: "public", err: notFound, exp: "Order not found"},
+		{id: "wrapped", err: errm.Wrap(notFound, "cannot load", "host", "db"), exp: "Order not found"},
+		{id: "outermost", err: errm.WithPublic(errm.Wrap(notFound, "cannot load"), "Try again later"), exp: "Try again later"},
+		{id: "fmt_wrap", err: fmt.Errorf("load: %w", notFound), exp: "Order not found"},
+		{id: "external_public", err: errm.WithPublic(io.EOF, "Upload interrupted"), exp: "Upload interrupted"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if got := errm.PublicMessage(tc.err); got != tc.exp {
+				t.Errorf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+
+	if exp := "no rows query=SELECT * FROM orders"; notFound.Error() != exp {
+		t.Errorf("expected %s, got %s", exp, notFound)
+	}
+	if !errm.IsCode(notFound, errm.NotFound) || errm.WithPublic(nil, "x") != nil {
+		t.Errorf("expected the code and nil for nil error")
+	}
+	if external := errm.WithPublic(io.EOF, "x"); external.Error() != "EOF" || !errm.Is(external, io.EOF) {
+		t.Errorf("expected EOF, got %s", external)
+	}
+
+	decoded, _ := errm.Decode(must(errm.Encode(errm.Wrap(notFound, "cannot load"))))
+	if got := errm.PublicMessage(decoded); got != "Order not found" {
+		t.Errorf("expected %q, got %q", "Order not found", got)
+	}
+}
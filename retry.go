@@ -0,0 +1,200 @@
+package errm
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// retryMark tells whether an operation that failed with an error can be retried.
+type retryMark uint8
+
+const (
+	retryUnknown retryMark = iota
+	retryRetryable
+	retryPermanent
+)
+
+// MarkRetryable returns the error marked as retryable, so [IsRetryable] returns true for it and errors that wrap it.
+// It returns nil if you provide a nil error.
+func MarkRetryable(err error) error {
+	return mark(err, retryRetryable)
+}
+
+// MarkPermanent returns the error marked as permanent, so [IsRetryable] returns false for it and errors that wrap it,
+// and [Retry] stops on it. It returns nil if you provide a nil error.
+func MarkPermanent(err error) error {
+	return mark(err, retryPermanent)
+}
+
+func mark(err error, m retryMark) error {
+	if err == nil {
+		return nil
+	}
+	return annotate(err, func(l *layer) {
+		l.retry = m
+	})
+}
+
+// IsRetryable reports whether an operation that failed with the error can be retried.
+// The outermost mark of [MarkRetryable] or [MarkPermanent] in the error chain decides.
+// If there is no mark, the error is retryable if any error in its chain, including errors of [List] and [Set]
+// and causes of [WrapMany], is retryable: it is a timeout like [net.Error] with Timeout() or [context.DeadlineExceeded],
+// it is a [syscall.ECONNRESET] except on Plan 9 or it has one of [Unavailable], [DeadlineExceeded] and [ResourceExhausted] codes.
+// [context.Canceled] is permanent.
+func IsRetryable(err error) bool {
+	return retryable(err) == retryRetryable
+}
+
+// retryable returns the mark of the error chain, see [IsRetryable].
+func retryable(err error) retryMark {
+	if err == nil {
+		return retryUnknown
+	}
+	if errs, ok := collectorErrors(err); ok {
+		return retryableAny(errs)
+	}
+
+	e, ok := err.(errorImpl)
+	if !ok || e.top == nil {
+		if m := retrySignal(err); m != retryUnknown {
+			return m
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() []error }:
+			return retryableAny(x.Unwrap())
+		case interface{ Unwrap() error }:
+			return retryable(x.Unwrap())
+		}
+		return retryUnknown
+	}
+
+	var causes []error
+	for l := e.top; l != nil; l = l.next {
+		if l.retry != retryUnknown {
+			return l.retry
+		}
+		causes = append(causes, l.causes...)
+		if l.next == nil && l.cause != nil {
+			causes = append(causes, l.cause)
+		}
+	}
+	switch CodeOf(err) {
+	case Unavailable, DeadlineExceeded, ResourceExhausted:
+		return retryRetryable
+	}
+	return retryableAny(causes)
+}
+
+// retryableAny returns retryable if any error is retryable or permanent if any error is permanent.
+func retryableAny(errs []error) retryMark {
+	out := retryUnknown
+	for _, err := range errs {
+		switch retryable(err) {
+		case retryRetryable:
+			return retryRetryable
+		case retryPermanent:
+			out = retryPermanent
+		}
+	}
+	return out
+}
+
+// retrySignal returns the mark of an error of the standard library.
+func retrySignal(err error) retryMark {
+	if err == context.Canceled {
+		return retryPermanent
+	}
+	if err == context.DeadlineExceeded {
+		return retryRetryable
+	}
+	if isConnReset(err) {
+		return retryRetryable
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return retryRetryable
+	}
+	return retryUnknown
+}
+
+// RetryOption configures [Retry].
+type RetryOption func(*retryConfig)
+
+type retryConfig struct {
+	attempts int
+	initial  time.Duration
+	max      time.Duration
+	noJitter bool
+}
+
+// MaxAttempts sets the maximum number of attempts of [Retry], it is 3 by default.
+func MaxAttempts(n int) RetryOption {
+	return func(c *retryConfig) {
+		c.attempts = max(n, 1)
+	}
+}
+
+// Backoff sets the delay before the second attempt of [Retry] and the maximum delay, every next delay is doubled.
+// They are 100ms and 10s by default.
+func Backoff(initial, maxDelay time.Duration) RetryOption {
+	return func(c *retryConfig) {
+		c.initial = initial
+		c.max = maxDelay
+	}
+}
+
+// NoJitter makes [Retry] wait exactly the backoff delay. By default a delay is chosen randomly between
+// a half of the backoff delay and the whole delay, so clients that failed together don't retry together.
+func NoJitter() RetryOption {
+	return func(c *retryConfig) {
+		c.noJitter = true
+	}
+}
+
+// Retry calls fn until it succeeds, returns a permanent error, see [MarkPermanent], or the attempts are over.
+// Errors that are not marked are retried. It waits with an exponential backoff and jitter between attempts.
+// It returns nil if an attempt succeeded, otherwise it returns a [List] error of errors of all attempts
+// with "attempt" fields starting from 1, and the error of the context if it is done while waiting.
+// The field is added to the outermost message of an error like [With] does it:
+//
+//	err := errm.Retry(ctx, func(ctx context.Context) error {
+//		return client.Send(ctx, msg)
+//	}, errm.MaxAttempts(5))
+//	// send attempt=1: connection reset; send attempt=2: connection reset; send attempt=3: invalid message
+func Retry(ctx context.Context, fn func(ctx context.Context) error, opts ...RetryOption) error {
+	c := retryConfig{attempts: 3, initial: 100 * time.Millisecond, max: 10 * time.Second}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	errs := NewListWithCapacity(c.attempts)
+	delay := c.initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		errs.Add(With(err, "attempt", attempt))
+		if attempt >= c.attempts || retryable(err) == retryPermanent {
+			return errs.Err()
+		}
+
+		wait := min(delay, c.max)
+		if !c.noJitter && wait > 0 {
+			wait = wait/2 + rand.N(wait/2+1)
+		}
+		if delay < c.max {
+			delay *= 2
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			errs.Add(Wrap(ctx.Err(), "retry is stopped"))
+			return errs.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package errm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestIsRetryable(t *testing.T) {
+	list := errm.NewList()
+	list.New("invalid row")
+	list.Add(errm.MarkRetryable(errm.New("lock timeout")))
+
+	permanentList := errm.NewList()
+	permanentList.Add(errm.MarkPermanent(io.EOF))
+	permanentList.New("x")
+
+	set := errm.NewSet()
+	set.Wrap(context.DeadlineExceeded, "query")
+
+	testCases := []struct {
+		id  string
+		err error
+		exp bool
+	}{
+		{id: "nil", err: nil, exp: false},
+		{id: "plain", err: errm.New("x"), exp: false},
+		{id: "marked", err: errm.MarkRetryable(errm.New("x")), exp: true},
+		{id: "marked_external", err: errm.MarkRetryable(io.EOF), exp: true},
+		{id: "wrapped_mark", err: errm.Wrap(errm.MarkRetryable(errm.New("x")), "y"), exp: true},
+		{id: "fmt_wrapped_mark", err: fmt.Errorf("y: %w", errm.MarkRetryable(errm.New("x"))), exp: true},
+		{id: "outermost_mark", err: errm.MarkPermanent(errm.Wrap(errm.MarkRetryable(errm.New("x")), "y")), exp: false},
+		{id: "permanent_timeout", err: errm.MarkPermanent(errm.Wrap(context.DeadlineExceeded, "y")), exp: false},
+		{id: "deadline", err: errm.Wrap(context.DeadlineExceeded, "query"), exp: true},
+		{id: "canceled", err: errm.Wrap(context.Canceled, "query"), exp: false},
+		{id: "os_deadline", err: errm.Wrap(os.ErrDeadlineExceeded, "read"), exp: true},
+		{id: "net_timeout", err: &net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, exp: true},
+		{id: "code", err: errm.New("down", errm.Unavailable), exp: true},
+		{id: "code_not_retryable", err: errm.New("x", errm.NotFound), exp: false},
+		{id: "errorf_cause", err: errm.Errorf("query: %w", context.DeadlineExceeded), exp: true},
+		{id: "wrap_many", err: errm.WrapMany([]error{io.EOF, context.DeadlineExceeded}, "sync"), exp: true},
+		{id: "list", err: list.Err(), exp: true},
+		{id: "permanent_list", err: permanentList.Err(), exp: false},
+		{id: "set", err: set.Err(), exp: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if got := errm.IsRetryable(tc.err); got != tc.exp {
+				t.Errorf("expected %t, got %t", tc.exp, got)
+			}
+		})
+	}
+
+	err := errm.MarkRetryable(errm.New("x", "id", 1))
+	if err.Error() != "x id=1" || errm.MarkPermanent(nil) != nil {
+		t.Errorf("expected the same message, got %s", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	fast := errm.Backoff(time.Millisecond, 2*time.Millisecond)
+
+	calls := 0
+	err := errm.Retry(ctx, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errm.New("timeout", "call", calls)
+		}
+		return nil
+	}, fast)
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls, got %d %v", calls, err)
+	}
+
+	calls = 0
+	err = errm.Retry(ctx, func(context.Context) error {
+		calls++
+		return errm.Wrap(context.DeadlineExceeded, "query")
+	}, fast, errm.MaxAttempts(4), errm.NoJitter())
+	exp := "query attempt=1: context deadline exceeded; query attempt=2: context deadline exceeded; " +
+		"query attempt=3: context deadline exceeded; query attempt=4: context deadline exceeded"
+	if calls != 4 || err == nil || err.Error() != exp {
+		t.Errorf("expected %s, got %v", exp, err)
+	}
+	if !errm.Is(err, context.DeadlineExceeded) || !errm.IsRetryable(err) {
+		t.Errorf("expected true, got false")
+	}
+
+	calls = 0
+	err = errm.Retry(ctx, func(context.Context) error {
+		calls++
+		if calls == 2 {
+			return errm.MarkPermanent(errm.New("invalid message"))
+		}
+		return io.EOF
+	}, fast, errm.MaxAttempts(5))
+	if exp := "EOF attempt=1; invalid message attempt=2"; calls != 2 || err == nil || err.Error() != exp {
+		t.Errorf("expected %s, got %v", exp, err)
+	}
+
+	// It is the example in the doc of Retry.
+	calls = 0
+	err = errm.Retry(ctx, func(context.Context) error {
+		calls++
+		if calls == 3 {
+			return errm.MarkPermanent(errm.Wrap(errors.New("invalid message"), "send"))
+		}
+		return errm.Wrap(errors.New("connection reset"), "send")
+	}, fast, errm.MaxAttempts(5))
+	if exp := "send attempt=1: connection reset; send attempt=2: connection reset; send attempt=3: invalid message"; err == nil || err.Error() != exp {
+		t.Errorf("expected %s, got %v", exp, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	calls = 0
+	err = errm.Retry(canceled, func(context.Context) error {
+		calls++
+		cancel()
+		return io.EOF
+	}, errm.Backoff(time.Hour, time.Hour))
+	if exp := "EOF attempt=1; retry is stopped: context canceled"; calls != 1 || err == nil || err.Error() != exp {
+		t.Errorf("expected %s, got %v", exp, err)
+	}
+}
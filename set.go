@@ -1,7 +1,11 @@
 package errm
 
 import (
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Set object is useful for collecting multiple unique errors into a single error,
@@ -9,62 +13,157 @@ import (
 // It is not very optimal thing, because it is calling err.Error() to make a key for the map.
 // So you have time-overhead caused by Error() and space-overhead because it stores an error twice (string key and value).
 // But you can win with it versus [List] when you have a lot of similar errors.
+// Use [NewSetWithKey] to choose what errors are the same, e.g. [KeyByTemplate] or [KeyByFingerprint].
+// Errors are kept in the order of the first insertion, use [SortedOrder] to get them sorted by message.
+// Set counts occurrences of every error, use [Set.Entries] to get them.
 type Set struct {
-	errs map[string]error
+	index   map[string]int
+	entries []SetEntry
+	key     func(error) string
+	now     func() time.Time // it is nil if timestamps are not tracked, see [Clock]
+	samples int
+	sorted  bool
+}
+
+// SetEntry is a unique error from [Set] with the statistics of its occurrences.
+type SetEntry struct {
+	// Err is the last added error with this key.
+	Err error
+	// Count is the number of times the error was added.
+	Count int
+	// FirstSeen is the time when the error was added for the first time, it is zero if the [Clock] is nil.
+	FirstSeen time.Time
+	// LastSeen is the time when the error was added for the last time, it is zero if the [Clock] is nil.
+	LastSeen time.Time
+	// Samples are the first added instances of the error, see [KeepSamples].
+	Samples []error
+}
+
+// String returns an error message with the number of occurrences if there are more than one,
+// e.g. "connection refused host=db (x42)".
+func (e SetEntry) String() string {
+	msg := e.Err.Error()
+	if e.Count <= 1 || msg == "" {
+		return msg
+	}
+	return msg + " (x" + strconv.Itoa(e.Count) + ")"
+}
+
+// SetOption configures a [Set] during creation.
+type SetOption func(*Set)
+
+// SortedOrder makes [Set] return errors sorted by their messages instead of the insertion order.
+func SortedOrder() SetOption {
+	return func(s *Set) {
+		s.sorted = true
+	}
+}
+
+// KeepSamples makes [Set] keep up to n first instances of every error in [SetEntry.Samples].
+func KeepSamples(n int) SetOption {
+	return func(s *Set) {
+		s.samples = n
+	}
+}
+
+// Clock sets the function that returns the current time for [SetEntry.FirstSeen] and [SetEntry.LastSeen],
+// it is [time.Now] by default. Use Clock(nil) to not read the clock on every [Set.Add]
+// if you don't need timestamps, e.g. on hot paths.
+func Clock(now func() time.Time) SetOption {
+	return func(s *Set) {
+		s.now = now
+	}
 }
 
 // NewSet returns a new [Set] instance with an empty underlying map.
 // Working with [Set] will cause allocations, use [NewSetWithCapacity] if you know the number of unique errors.
-func NewSet() *Set {
-	return &Set{errs: make(map[string]error)}
+func NewSet(opts ...SetOption) *Set {
+	return NewSetWithCapacity(0, opts...)
 }
 
 // NewSetWithCapacity returns a new [Set] instance with an initialized underlying map.
 // It may be useful if you know the number of errors and you want to optimize code.
-func NewSetWithCapacity(capacity int) *Set {
-	return &Set{errs: make(map[string]error, capacity)}
+func NewSetWithCapacity(capacity int, opts ...SetOption) *Set {
+	s := &Set{
+		index:   make(map[string]int, capacity),
+		entries: make([]SetEntry, 0, capacity),
+		key:     KeyByMessage,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewSetWithKey returns a new [Set] instance that uses the provided function to make a key for the map.
+// Errors with the same key are the same error for the [Set]. There are built-in strategies:
+// [KeyByMessage] (default), [KeyByTemplate], [KeyByRootCause] and [KeyByFingerprint].
+func NewSetWithKey(key func(error) string, opts ...SetOption) *Set {
+	s := NewSet(opts...)
+	s.key = key
+	return s
 }
 
 // Add sets an error to the underlying map. It is noop if you provide a nil error.
-// It will call err.Error() to make a key for the map.
+// It will call err.Error() or the key function of the [Set] to make a key for the map.
+// If there is an error with the same key, it is replaced and its counter is incremented,
+// but it keeps the position of the first one.
 func (e *Set) Add(err error) {
 	if err == nil {
 		return
 	}
-	e.errs[err.Error()] = err
+	key := e.key(err)
+	var now time.Time
+	if e.now != nil {
+		now = e.now()
+	}
+	if i, ok := e.index[key]; ok {
+		entry := &e.entries[i]
+		entry.Err = err
+		entry.Count++
+		entry.LastSeen = now
+		if len(entry.Samples) < e.samples {
+			entry.Samples = append(entry.Samples, err)
+		}
+		return
+	}
+	entry := SetEntry{Err: err, Count: 1, FirstSeen: now, LastSeen: now}
+	if e.samples > 0 {
+		entry.Samples = []error{err}
+	}
+	e.index[key] = len(e.entries)
+	e.entries = append(e.entries, entry)
 }
 
 // New creates an error using [New] and sets in to the underlying map.
-// It will call err.Error() to make a key for the map.
+// It will call err.Error() or the key function of the [Set] to make a key for the map.
 func (e *Set) New(msg string, fields ...any) {
-	err := New(msg, fields...)
-	e.errs[err.Error()] = err
+	e.Add(New(msg, fields...))
 }
 
 // Errorf creates an error using [Errorf] and sets in to the underlying map.
-// It will call err.Error() to make a key for the map.
+// It will call err.Error() or the key function of the [Set] to make a key for the map.
 func (e *Set) Errorf(format string, args ...any) {
-	err := Errorf(format, args...)
-	e.errs[err.Error()] = err
+	e.Add(Errorf(format, args...))
 }
 
 // Wrap creates an error using [Wrap] and sets in to the underlying map.
-// It will call err.Error() to make a key for the map.
+// It will call err.Error() or the key function of the [Set] to make a key for the map.
 func (e *Set) Wrap(err error, format string, fields ...any) {
-	err = Wrap(err, format, fields...)
-	e.errs[err.Error()] = err
+	e.Add(Wrap(err, format, fields...))
 }
 
 // Wrapf creates an error using [Wrapf] and sets in to the underlying map.
-// It will call err.Error() to make a key for the map.
+// It will call err.Error() or the key function of the [Set] to make a key for the map.
 func (e *Set) Wrapf(err error, format string, args ...any) {
-	err = Wrapf(err, format, args...)
-	e.errs[err.Error()] = err
+	e.Add(Wrapf(err, format, args...))
 }
 
 // Has returns true if the [Set] contains the given error.
 func (e *Set) Has(err error, errs ...error) bool {
-	for _, e := range e.errs {
+	for _, entry := range e.entries {
+		e := entry.Err
 		if Is(e, err) {
 			return true
 		}
@@ -77,9 +176,37 @@ func (e *Set) Has(err error, errs ...error) bool {
 	return false
 }
 
+// Errors returns a copy of errors from [Set] in the insertion order or sorted by message if [SortedOrder] is used.
+func (e *Set) Errors() []error {
+	entries := e.ordered()
+	errs := make([]error, len(entries))
+	for i, entry := range entries {
+		errs[i] = entry.Err
+	}
+	return errs
+}
+
+// Entries returns a copy of entries from [Set] with occurrence statistics in the same order as [Set.Errors].
+func (e *Set) Entries() []SetEntry {
+	entries := e.ordered()
+	for i := range entries {
+		entries[i].Samples = slices.Clone(entries[i].Samples)
+	}
+	return entries
+}
+
+// MaxSeverity returns the maximum [Severity] of errors in the [Set] or 0 if it is empty.
+func (e *Set) MaxSeverity() Level {
+	var out Level
+	for _, entry := range e.entries {
+		out = max(out, Severity(entry.Err))
+	}
+	return out
+}
+
 // Err returns current [Set] instance as error interface or nil if it is empty.
 func (e *Set) Err() error {
-	if len(e.errs) == 0 {
+	if len(e.entries) == 0 {
 		return nil
 	}
 	return setError{e}
@@ -87,17 +214,26 @@ func (e *Set) Err() error {
 
 // Empty return true if the [Set] collector is empty.
 func (e *Set) Empty() bool {
-	return len(e.errs) == 0
+	return len(e.entries) == 0
 }
 
 // Clear removes an underlying map of errors.
 func (e *Set) Clear() {
-	e.errs = make(map[string]error)
+	e.index = make(map[string]int)
+	e.entries = nil
 }
 
 // Len returns the number of errors in [Set].
 func (e *Set) Len() int {
-	return len(e.errs)
+	return len(e.entries)
+}
+
+func (e *Set) ordered() []SetEntry {
+	entries := slices.Clone(e.entries)
+	if e.sorted {
+		sortByMessage(entries, func(entry SetEntry) error { return entry.Err })
+	}
+	return entries
 }
 
 // SafeSet object is useful for collecting multiple unique errors from different goroutines into a single error,
@@ -109,17 +245,25 @@ type SafeSet struct {
 
 // NewSafeSet returns a new [SafeSet] instance with an empty underlying slice.
 // Working with [SafeSet] will cause allocations, use [NewSafeSetWithCapacity] if you know the number of unique errors.
-func NewSafeSet() *SafeSet {
+func NewSafeSet(opts ...SetOption) *SafeSet {
+	return &SafeSet{
+		set: NewSet(opts...),
+	}
+}
+
+// NewSafeSetWithKey returns a new [SafeSet] instance that uses the provided function to make a key for the map.
+// See [NewSetWithKey] for details.
+func NewSafeSetWithKey(key func(error) string, opts ...SetOption) *SafeSet {
 	return &SafeSet{
-		set: NewSet(),
+		set: NewSetWithKey(key, opts...),
 	}
 }
 
 // NewSafeSetWithCapacity returns a new [SafeSet] instance with an initialized underlying slice.
 // It may be useful if you know the number of errors and you want to optimize code.
-func NewSafeSetWithCapacity(capacity int) *SafeSet {
+func NewSafeSetWithCapacity(capacity int, opts ...SetOption) *SafeSet {
 	return &SafeSet{
-		set: NewSetWithCapacity(capacity),
+		set: NewSetWithCapacity(capacity, opts...),
 	}
 }
 
@@ -169,6 +313,22 @@ func (e *SafeSet) Has(err error) bool {
 	return e.set.Has(err)
 }
 
+// Errors returns a copy of errors from [SafeSet] in the same order as [Set.Errors].
+// It is safe for concurrent/parallel usage.
+func (e *SafeSet) Errors() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.set.Errors()
+}
+
+// Entries returns a copy of entries from [SafeSet] with occurrence statistics in the same order as [Set.Errors].
+// It is safe for concurrent/parallel usage.
+func (e *SafeSet) Entries() []SetEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.set.Entries()
+}
+
 // Empty return true if the [SafeSet] collector is empty. It is safe for concurrent/parallel usage.
 func (e *SafeSet) Empty() bool {
 	e.mu.Lock()
@@ -191,6 +351,14 @@ func (e *SafeSet) Clear() {
 	e.set.Clear()
 }
 
+// MaxSeverity returns the maximum [Severity] of errors in the [SafeSet] or 0 if it is empty.
+// It is safe for concurrent/parallel usage.
+func (e *SafeSet) MaxSeverity() Level {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.set.MaxSeverity()
+}
+
 // Len returns the number of errors in [SafeSet]. It is safe for concurrent/parallel usage.
 func (e *SafeSet) Len() int {
 	e.mu.Lock()
@@ -201,12 +369,39 @@ func (e *SafeSet) Len() int {
 type setError struct{ *Set }
 
 func (e setError) Error() string {
-	if len(e.errs) == 0 {
-		return ""
+	return joinEntries(e.ordered())
+}
+
+// joinEntries joins messages of entries using '; ' as separator, like [JoinErrors] does.
+func joinEntries(entries []SetEntry) string {
+	var b []byte
+	for _, entry := range entries {
+		msg := entry.String()
+		if msg == "" {
+			continue
+		}
+		if len(b) > 0 {
+			b = append(b, ';', ' ')
+		}
+		b = append(b, msg...)
+	}
+	return string(b)
+}
+
+// sortByMessage sorts items by messages of their errors, it calls Error() only once for every item.
+func sortByMessage[T any](items []T, errOf func(T) error) {
+	msgs := make([]string, len(items))
+	idx := make([]int, len(items))
+	for i, item := range items {
+		msgs[i] = errOf(item).Error()
+		idx[i] = i
 	}
-	errs := make([]error, 0, len(e.errs))
-	for _, k := range e.errs {
-		errs = append(errs, k)
+	slices.SortStableFunc(idx, func(a, b int) int {
+		return strings.Compare(msgs[a], msgs[b])
+	})
+	sorted := make([]T, len(items))
+	for i, j := range idx {
+		sorted[i] = items[j]
 	}
-	return JoinErrors(errs...).Error()
+	copy(items, sorted)
 }
@@ -0,0 +1,130 @@
+package errm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Level is a severity of an error, see [Severity]. Levels are ordered, so they can be compared.
+type Level int8
+
+// Severity levels of errors, the zero level means that there is no error.
+const (
+	LevelDebug Level = iota + 1
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelCritical
+)
+
+// String returns a name of the level, e.g. "warn".
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelCritical:
+		return "critical"
+	}
+	return ""
+}
+
+// SlogLevel returns the level of log/slog that corresponds to the severity, [LevelCritical] is higher than slog.LevelError.
+func (l Level) SlogLevel() slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelCritical:
+		return slog.LevelError + 4
+	}
+	return slog.LevelError
+}
+
+// WithSeverity returns the error with the severity level, it overrides the level derived from the code of the error.
+// It returns nil if you provide a nil error.
+func WithSeverity(err error, level Level) error {
+	if err == nil {
+		return nil
+	}
+	return annotate(err, func(l *layer) {
+		l.severity = level
+	})
+}
+
+// Severity returns the outermost level set using [WithSeverity] in the error chain or the level derived from
+// the code of the error: [LevelDebug] for [Canceled], [LevelInfo] for codes of invalid requests like [NotFound]
+// and [InvalidArgument], [LevelWarn] for [Unavailable], [DeadlineExceeded] and [ResourceExhausted]
+// and [LevelError] for other errors. It returns the maximum level of errors of collectors, e.g. [List] and [Set],
+// see [List.MaxSeverity]. It returns 0 for a nil error.
+func Severity(err error) Level {
+	if err == nil {
+		return 0
+	}
+	if level, ok := severity(err); ok {
+		return level
+	}
+	switch CodeOf(err) {
+	case Canceled:
+		return LevelDebug
+	case InvalidArgument, NotFound, AlreadyExists, PermissionDenied, Unauthenticated, FailedPrecondition:
+		return LevelInfo
+	case Unavailable, DeadlineExceeded, ResourceExhausted:
+		return LevelWarn
+	}
+	return LevelError
+}
+
+// severity returns the level that is set explicitly in the error chain or the maximum level of a collector.
+func severity(err error) (Level, bool) {
+	for err != nil {
+		if errs, ok := collectorErrors(err); ok {
+			return maxSeverity(errs), true
+		}
+		e, ok := err.(errorImpl)
+		if !ok || e.top == nil {
+			err = errors.Unwrap(err)
+			continue
+		}
+		for l := e.top; l != nil; l = l.next {
+			if l.severity != 0 {
+				return l.severity, true
+			}
+			if l.next == nil {
+				err = l.cause
+			}
+		}
+	}
+	return 0, false
+}
+
+func maxSeverity(errs []error) Level {
+	var out Level
+	for _, err := range errs {
+		out = max(out, Severity(err))
+	}
+	return out
+}
+
+// Log logs the message with the error using the logger at the level of [Severity] of the error,
+// the message of the error is added as the "error" attribute before other arguments, use [StackForLogger] to add the stack trace.
+// It uses slog.Default() if the logger is nil.
+// It is noop for a nil error.
+func Log(ctx context.Context, logger *slog.Logger, msg string, err error, args ...any) {
+	if err == nil {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Log(ctx, Severity(err).SlogLevel(), msg, append([]any{slog.String("error", err.Error())}, args...)...)
+}
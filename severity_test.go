@@ -0,0 +1,106 @@
+package errm_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestSeverity(t *testing.T) {
+	warnings := errm.NewList()
+	warnings.New("row skipped", errm.InvalidArgument, "row", 1)
+	warnings.Add(errm.WithSeverity(errm.New("slow query"), errm.LevelWarn))
+
+	testCases := []struct {
+		id  string
+		err error
+		exp errm.Level
+	}{
+		{id: "nil", err: nil, exp: 0},
+		{id: "no_code", err: errm.New("x"), exp: errm.LevelError},
+		{id: "external", err: io.EOF, exp: errm.LevelError},
+		{id: "canceled", err: errm.New("x", errm.Canceled), exp: errm.LevelDebug},
+		{id: "not_found", err: errm.New("x", errm.NotFound), exp: errm.LevelInfo},
+		{id: "unavailable", err: errm.New("x", errm.Unavailable), exp: errm.LevelWarn},
+		{id: "internal", err: errm.New("x", errm.Internal), exp: errm.LevelError},
+		{id: "explicit", err: errm.WithSeverity(errm.New("x", errm.NotFound), errm.LevelCritical), exp: errm.LevelCritical},
+		{id: "wrapped", err: errm.Wrap(errm.WithSeverity(errm.New("x"), errm.LevelWarn), "y", errm.Internal), exp: errm.LevelWarn},
+		{id: "outermost", err: errm.WithSeverity(errm.Wrap(errm.WithSeverity(errm.New("x"), errm.LevelWarn), "y"), errm.LevelDebug), exp: errm.LevelDebug},
+		{id: "external_explicit", err: fmt.Errorf("y: %w", errm.WithSeverity(io.EOF, errm.LevelInfo)), exp: errm.LevelInfo},
+		{id: "list", err: warnings.Err(), exp: errm.LevelWarn},
+		{id: "wrapped_list", err: errm.Wrap(warnings.Err(), "batch"), exp: errm.LevelWarn},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if got := errm.Severity(tc.err); got != tc.exp {
+				t.Errorf("expected %s, got %s", tc.exp, got)
+			}
+		})
+	}
+
+	if warnings.MaxSeverity() != errm.LevelWarn {
+		t.Errorf("expected warn, got %s", warnings.MaxSeverity())
+	}
+	warnings.Add(errm.WithSeverity(errm.New("disk failed"), errm.LevelCritical))
+	if warnings.MaxSeverity() != errm.LevelCritical {
+		t.Errorf("expected critical, got %s", warnings.MaxSeverity())
+	}
+	if errm.NewList().MaxSeverity() != 0 || errm.NewSafeList().MaxSeverity() != 0 {
+		t.Errorf("expected 0 for an empty list")
+	}
+
+	set := errm.NewSafeSet()
+	set.New("row skipped", errm.InvalidArgument)
+	set.New("timeout", errm.DeadlineExceeded)
+	if set.MaxSeverity() != errm.LevelWarn {
+		t.Errorf("expected warn, got %s", set.MaxSeverity())
+	}
+
+	sharded := errm.NewShardedList()
+	compact := errm.NewCompactSet()
+	expiring := errm.NewExpiringSet(time.Minute, 10)
+	if sharded.MaxSeverity() != 0 || compact.MaxSeverity() != 0 || expiring.MaxSeverity() != 0 {
+		t.Errorf("expected 0 for empty collectors")
+	}
+	for _, err := range []error{errm.New("row skipped", errm.InvalidArgument), errm.New("timeout", errm.DeadlineExceeded)} {
+		sharded.Add(err)
+		compact.Add(err)
+		expiring.Add(err)
+	}
+	if sharded.MaxSeverity() != errm.LevelWarn || compact.MaxSeverity() != errm.LevelWarn || expiring.MaxSeverity() != errm.LevelWarn {
+		t.Errorf("expected warn, got %s, %s and %s", sharded.MaxSeverity(), compact.MaxSeverity(), expiring.MaxSeverity())
+	}
+	if errm.Severity(compact.Err()) != errm.LevelWarn || errm.Severity(sharded.Err()) != errm.LevelWarn {
+		t.Errorf("expected warn for errors of collectors")
+	}
+	if err := errm.WithSeverity(errm.New("x", "id", 1), errm.LevelWarn); err.Error() != "x id=1" || errm.WithSeverity(nil, errm.LevelWarn) != nil {
+		t.Errorf("expected the same message, got %s", err)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	errm.Log(context.Background(), logger, "cannot load", errm.New("not found", errm.NotFound, "id", 1), "user", "bob")
+	errm.Log(context.Background(), logger, "disk", errm.WithSeverity(errm.New("failed"), errm.LevelCritical))
+	errm.Log(context.Background(), logger, "nothing", nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], `level=INFO msg="cannot load" error="not found id=1" user=bob`) {
+		t.Errorf("expected info record, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `level=ERROR+4 msg=disk error=failed`) {
+		t.Errorf("expected critical record, got %s", lines[1])
+	}
+}
@@ -0,0 +1,172 @@
+package errm
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// ShardedList object is useful for collecting multiple errors from a lot of goroutines into a single error,
+// in which error messages are separated by a ";". It is safe for concurrent/parallel usage.
+// It has the same API as [SafeList], but it spreads errors between several independently locked shards,
+// so goroutines rarely wait for each other. Use it instead of [SafeList] when there is a heavy fan-out
+// and the mutex of [SafeList] becomes a contention point.
+// The order of errors from different goroutines is not defined, shards are merged in [ShardedList.Err].
+type ShardedList struct {
+	shards []listShard
+	mask   uint32
+}
+
+// listShard is padded to a cache line to avoid false sharing between shards.
+type listShard struct {
+	mu   sync.Mutex
+	errs []error
+	_    [64]byte
+}
+
+// NewShardedList returns a new [ShardedList] instance with a number of shards based on GOMAXPROCS.
+func NewShardedList() *ShardedList {
+	return NewShardedListWithCapacity(0)
+}
+
+// NewShardedListWithCapacity returns a new [ShardedList] instance with initialized underlying slices.
+// Capacity is the expected total number of errors, it is divided between shards.
+func NewShardedListWithCapacity(capacity int) *ShardedList {
+	n := shardsNumber()
+	e := &ShardedList{
+		shards: make([]listShard, n),
+		mask:   uint32(n - 1),
+	}
+	if perShard := capacity / n; perShard > 0 {
+		for i := range e.shards {
+			e.shards[i].errs = make([]error, 0, perShard)
+		}
+	}
+	return e
+}
+
+// Add appends an error to one of the shards. It is noop if you provide an empty error.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) Add(err error) {
+	if err == nil {
+		return
+	}
+	e.add(err)
+}
+
+// New creates an error using [New] and appends it to one of the shards.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) New(err string, fields ...any) {
+	e.add(New(err, fields...))
+}
+
+// Errorf creates an error using [Errorf] and appends it to one of the shards.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) Errorf(format string, args ...any) {
+	e.add(Errorf(format, args...))
+}
+
+// Wrap creates an error using [Wrap] and appends it to one of the shards.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) Wrap(err error, format string, fields ...any) {
+	e.add(Wrap(err, format, fields...))
+}
+
+// Wrapf creates an error using [Wrapf] and appends it to one of the shards.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) Wrapf(err error, format string, args ...any) {
+	e.add(Wrapf(err, format, args...))
+}
+
+// Has returns true if the [ShardedList] contains the given error. It is safe for concurrent/parallel usage.
+func (e *ShardedList) Has(err error, errs ...error) bool {
+	for i := range e.shards {
+		s := &e.shards[i]
+		s.mu.Lock()
+		list := List{errs: s.errs}
+		ok := list.Has(err, errs...)
+		s.mu.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Err merges all shards and returns them as error interface or nil if [ShardedList] is empty.
+// Returned error is a snapshot, errors added after the call are not included in it.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) Err() error {
+	var list List
+	for i := range e.shards {
+		s := &e.shards[i]
+		s.mu.Lock()
+		list.errs = append(list.errs, s.errs...)
+		s.mu.Unlock()
+	}
+	return list.Err()
+}
+
+// MaxSeverity returns the maximum [Severity] of errors in the [ShardedList] or 0 if it is empty.
+// It is safe for concurrent/parallel usage.
+func (e *ShardedList) MaxSeverity() Level {
+	var out Level
+	for i := range e.shards {
+		s := &e.shards[i]
+		s.mu.Lock()
+		errs := s.errs // errors are only appended, so the elements can be read without the lock
+		s.mu.Unlock()
+		out = max(out, maxSeverity(errs))
+	}
+	return out
+}
+
+// Empty returns true if the [ShardedList] collector is empty. It is safe for concurrent/parallel usage.
+func (e *ShardedList) Empty() bool {
+	return e.Len() == 0
+}
+
+// NotEmpty returns true if the [ShardedList] collector has errors. It is safe for concurrent/parallel usage.
+func (e *ShardedList) NotEmpty() bool {
+	return e.Len() != 0
+}
+
+// Clear removes underlying slices of errors. It is safe for concurrent/parallel usage.
+func (e *ShardedList) Clear() {
+	for i := range e.shards {
+		s := &e.shards[i]
+		s.mu.Lock()
+		s.errs = nil
+		s.mu.Unlock()
+	}
+}
+
+// Len returns the number of errors in [ShardedList]. It is safe for concurrent/parallel usage.
+func (e *ShardedList) Len() int {
+	var n int
+	for i := range e.shards {
+		s := &e.shards[i]
+		s.mu.Lock()
+		n += len(s.errs)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (e *ShardedList) add(err error) {
+	// Global functions of math/rand/v2 use a per-thread state, so picking a shard doesn't contend.
+	s := &e.shards[rand.Uint32()&e.mask]
+	s.mu.Lock()
+	s.errs = append(s.errs, err)
+	s.mu.Unlock()
+}
+
+// shardsNumber returns GOMAXPROCS rounded up to a power of two to pick shards with a mask.
+func shardsNumber() int {
+	n := runtime.GOMAXPROCS(0)
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
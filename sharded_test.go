@@ -0,0 +1,130 @@
+package errm_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestShardedList(t *testing.T) {
+	s := errm.NewShardedList()
+	if !s.Empty() {
+		t.Errorf("expected empty, got %d", s.Len())
+	}
+	if s.Err() != nil {
+		t.Errorf("expected nil, got %s", s.Err())
+	}
+
+	err := errors.New("A")
+	const goroutines, perGoroutine = 64, 100
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				s.Add(err)
+				s.Add(nil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.Len() != goroutines*perGoroutine {
+		t.Errorf("expected %d, got %d", goroutines*perGoroutine, s.Len())
+	}
+	if !s.NotEmpty() {
+		t.Errorf("expected not empty")
+	}
+	if !s.Has(err) {
+		t.Errorf("expected true, got false")
+	}
+	if s.Has(errm.New("B")) {
+		t.Errorf("expected false, got true")
+	}
+
+	out := s.Err()
+	if !errm.Is(out, err) {
+		t.Errorf("expected true, got false")
+	}
+	if n := strings.Count(out.Error(), "A"); n != goroutines*perGoroutine {
+		t.Errorf("expected %d messages, got %d", goroutines*perGoroutine, n)
+	}
+
+	s.Clear()
+	if !s.Empty() {
+		t.Errorf("expected empty, got %d", s.Len())
+	}
+	if out.Error() == "" {
+		t.Errorf("expected snapshot to stay the same after Clear")
+	}
+}
+
+func TestShardedListMethods(t *testing.T) {
+	base := errm.New("base")
+	s := errm.NewShardedListWithCapacity(16)
+	s.New("new", "field", 1)
+	s.Errorf("errorf %d", 2)
+	s.Wrap(base, "wrap")
+	s.Wrapf(base, "wrapf %s", "x")
+
+	if s.Len() != 4 {
+		t.Errorf("expected 4, got %d", s.Len())
+	}
+	if !s.Has(errm.New("A"), base) {
+		t.Errorf("expected true, got false")
+	}
+
+	msg := s.Err().Error()
+	for _, exp := range []string{"new field=1", "errorf 2", "wrap: base", "wrapf x: base"} {
+		if !strings.Contains(msg, exp) {
+			t.Errorf("expected %q in %q", exp, msg)
+		}
+	}
+}
+
+var benchErr = errors.New("bench error")
+
+func BenchmarkSafeListAddParallel(b *testing.B) {
+	s := errm.NewSafeList()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Add(benchErr)
+		}
+	})
+}
+
+func BenchmarkShardedListAddParallel(b *testing.B) {
+	s := errm.NewShardedList()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Add(benchErr)
+		}
+	})
+}
+
+func BenchmarkSafeListNewParallel(b *testing.B) {
+	s := errm.NewSafeList()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.New("bench error", "id", 42)
+		}
+	})
+}
+
+func BenchmarkShardedListNewParallel(b *testing.B) {
+	s := errm.NewShardedList()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.New("bench error", "id", 42)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package errm
+
+import (
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// T creates a new error with a message template with named placeholders and pairs of fields, e.g.
+//
+//	errm.T("user {user_id} not found in {table}", "user_id", 42, "table", "users")
+//	// user 42 not found in users
+//
+// Placeholders are replaced by values of fields with the same keys, other fields are added in a field=val format.
+// Use "{{" and "}}" to write braces. Placeholders without a field stay as they are.
+// Unlike [Errorf], the error keeps the template and all fields, see [Template] and [Fields].
+func T(template string, fields ...any) error {
+	msg, inline, rest, _ := renderTemplate(template, fields)
+	l := &layer{msg: msg, format: template, fields: rest, inline: inline}
+	return newError(eris.New(buildErrorMessage(msg, rest)), l)
+}
+
+// Template returns the message template of the error: the template of [T], the format of [Errorf] and [Wrapf]
+// or the message of [New] and [Wrap] without fields. It returns the result of Error() for other errors.
+func Template(err error) string {
+	if err == nil {
+		return ""
+	}
+	e, ok := asError(err)
+	if !ok {
+		return err.Error()
+	}
+	return e.top.template()
+}
+
+// Fields returns pairs of fields of all layers of the error starting from the outermost one,
+// including fields interpolated into templates of [T]. Errors of this package wrapped by external errors
+// are included too. It returns nil for errors not created by this package.
+func Fields(err error) []any {
+	var out []any
+	for {
+		e, ok := asError(err)
+		if !ok {
+			return out
+		}
+		err = nil
+		for l := e.top; l != nil; l = l.next {
+			forEachField(l.inline, func(f Field) {
+				out = append(out, f.Key, f.Value())
+			})
+			l.eachField(func(f Field) {
+				out = append(out, f.Key, f.Value())
+			})
+			if l.next == nil {
+				err = l.cause
+			}
+		}
+	}
+}
+
+// renderTemplate replaces placeholders with values of fields,
+// it returns used fields and fields that are not in the template separately.
+// Placeholders without fields are kept as is, missing is true if there are such placeholders.
+func renderTemplate(template string, fields []any) (msg string, inline, rest []any, missing bool) {
+	values := make(map[string]Field, len(fields)/2)
+	forEachField(fields, func(f Field) {
+		values[f.Key] = f
+	})
+
+	used := make(map[string]bool, len(values))
+	out := strings.Builder{}
+	out.Grow(len(template) + len(values)*fieldAverageLength)
+
+	var buf [64]byte
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			out.WriteByte(c)
+			i++
+			continue
+		}
+		if c != '{' {
+			out.WriteByte(c)
+			continue
+		}
+		end := strings.IndexAny(template[i+1:], "{}")
+		if end <= 0 || template[i+1+end] != '}' {
+			out.WriteByte(c)
+			continue
+		}
+		key := template[i+1 : i+1+end]
+		f, ok := values[key]
+		if !ok {
+			out.WriteString(template[i : i+end+2])
+			missing = true
+		} else {
+			b, s := f.render(buf[:0])
+			out.Write(b)
+			out.WriteString(s)
+			used[key] = true
+		}
+		i += end + 1
+	}
+
+	for i := 0; i < len(fields); i++ {
+		switch key := fields[i].(type) {
+		case Field:
+			if used[key.Key] {
+				inline = append(inline, key)
+			} else {
+				rest = append(rest, key)
+			}
+		case string:
+			switch {
+			case i+1 >= len(fields):
+				rest = append(rest, key)
+			case used[key]:
+				inline = append(inline, key, fields[i+1])
+			default:
+				rest = append(rest, key, fields[i+1])
+			}
+			i++
+		default:
+			rest = append(rest, key)
+		}
+	}
+	return out.String(), inline, rest, missing
+}
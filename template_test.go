@@ -0,0 +1,103 @@
+package errm_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/maxbolgarin/errm"
+)
+
+func TestT(t *testing.T) {
+	testCases := []struct {
+		id  string
+		err error
+		exp string
+	}{
+		{
+			id:  "simple",
+			err: errm.T("user not found"),
+			exp: "user not found",
+		},
+		{
+			id:  "placeholders",
+			err: errm.T("user {user_id} not found in {table}", "user_id", 42, "table", "users"),
+			exp: "user 42 not found in users",
+		},
+		{
+			id:  "extra_fields",
+			err: errm.T("user {user_id} not found", "table", "users", "user_id", 42),
+			exp: "user 42 not found table=users",
+		},
+		{
+			id:  "missing_field",
+			err: errm.T("user {user_id} not found in {table}", "user_id", 42),
+			exp: "user 42 not found in {table}",
+		},
+		{
+			id:  "escapes",
+			err: errm.T("{{user_id}} is {user_id}, {} and {", "user_id", 42),
+			exp: "{user_id} is 42, {} and {",
+		},
+		{
+			id:  "repeated",
+			err: errm.T("{a}-{a}", "a", "x"),
+			exp: "x-x",
+		},
+		{
+			id:  "percent",
+			err: errm.T("{n}% done", "n", 50),
+			exp: "50% done",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.id, func(t *testing.T) {
+			if test.err.Error() != test.exp {
+				t.Errorf("expected %s, got %s", test.exp, test.err)
+			}
+		})
+	}
+}
+
+func TestTemplateAndFields(t *testing.T) {
+	tmpl := "user {user_id} not found in {table}"
+	err := errm.T(tmpl, "user_id", 42, "table", "users", "shard", 3)
+	if got := errm.Template(err); got != tmpl {
+		t.Errorf("expected %s, got %s", tmpl, got)
+	}
+
+	wrapped := errm.Wrapf(err, "handle %s", "request", "request_id", "abc")
+	if got := errm.Template(wrapped); got != "handle %s" {
+		t.Errorf("expected handle %%s, got %s", got)
+	}
+	exp := []any{"request_id", "abc", "user_id", 42, "table", "users", "shard", 3}
+	if got := errm.Fields(wrapped); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	if got := errm.Template(errm.New("not found", "id", 1)); got != "not found" {
+		t.Errorf("expected not found, got %s", got)
+	}
+	if got := errm.Template(errors.New("external")); got != "external" {
+		t.Errorf("expected external, got %s", got)
+	}
+	if got := errm.Fields(errors.New("external")); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+	external := errm.Wrap(fmt.Errorf("ctx: %w", wrapped), "retry", "attempt", 2)
+	exp = append([]any{"attempt", 2}, exp...)
+	if got := errm.Fields(external); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+
+	a := errm.T(tmpl, "user_id", 1, "table", "users")
+	b := errm.T(tmpl, "user_id", 2, "table", "orders")
+	if errm.KeyByTemplate(a) != errm.KeyByTemplate(b) {
+		t.Errorf("expected the same key, got %s and %s", errm.KeyByTemplate(a), errm.KeyByTemplate(b))
+	}
+	if errm.Fingerprint(a) != errm.Fingerprint(b) {
+		t.Errorf("expected the same fingerprint")
+	}
+}